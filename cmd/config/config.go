@@ -8,27 +8,237 @@ import (
 
 var Config *Configuration
 
+// MemoryDriver keeps data in process, for tests and demos.
+const MemoryDriver = "memory"
+
 type Configuration struct {
 	Server   ServerConfiguration
 	Database DatabaseConfiguration
+	Seed     SeedConfiguration
+	Cache    CacheConfiguration
+	Outbox   OutboxConfiguration
+}
+
+// OutboxConfiguration runs the relay that publishes the outbox table.
+// Publisher is log, file, nats or kafka; intervals are in seconds.
+type OutboxConfiguration struct {
+	Enabled          bool
+	Publisher        string
+	Interval         int
+	BatchSize        int `mapstructure:"batch_size"`
+	MaxAttempts      int `mapstructure:"max_attempts"`
+	RetryInterval    int `mapstructure:"retry_interval"`
+	MaxRetryInterval int `mapstructure:"max_retry_interval"`
+	Retention        int
+	File             string
+	Nats             NatsConfiguration
+	Kafka            KafkaConfiguration
+}
+
+type NatsConfiguration struct {
+	URL           string
+	SubjectPrefix string `mapstructure:"subject_prefix"`
+}
+
+type KafkaConfiguration struct {
+	Brokers     []string
+	TopicPrefix string `mapstructure:"topic_prefix"`
+}
+
+// CacheConfiguration puts a read-through cache in front of Get and List.
+// Driver is none, memory or redis; TTL is in seconds and Size bounds the
+// memory cache. The memory cache only sees the writes of its own instance,
+// so deployments running several need redis or none.
+type CacheConfiguration struct {
+	Driver string
+	TTL    int
+	Size   int
+	Redis  RedisConfiguration
+}
+
+type RedisConfiguration struct {
+	Addr     string
+	Password string
+	DB       int
+	// Prefix starts every key, so instances of other services can share
+	// the server.
+	Prefix string
+}
+
+// SeedConfiguration holds the defaults of the seed command: fixtures are
+// read from Dir/Profile.
+type SeedConfiguration struct {
+	Dir     string
+	Profile string
 }
 
 type DatabaseConfiguration struct {
-	Driver       string
+	// Driver is mysql, postgres, sqlite or memory.
+	Driver string
+	// DSN is handed to the driver as is; when empty it is built from the
+	// fields below.
+	DSN          string
 	Dbname       string
 	Username     string
 	Password     string
 	Host         string
 	Port         string
-	MaxLifetime  int
-	MaxOpenConns int
-	MaxIdleConns int
+	MaxLifetime  int `mapstructure:"max_lifetime"`
+	MaxOpenConns int `mapstructure:"max_open_conns"`
+	MaxIdleConns int `mapstructure:"max_idle_conns"`
+	// AutoMigrate applies pending migrations at startup; otherwise run
+	// the migrate command.
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+	// ConnectTimeout bounds the startup retries; intervals double from
+	// RetryInterval up to RetryMaxInterval. All values are in seconds.
+	ConnectTimeout   int `mapstructure:"connect_timeout"`
+	RetryInterval    int `mapstructure:"retry_interval"`
+	RetryMaxInterval int `mapstructure:"retry_max_interval"`
+
+	// SSLMode takes the postgres names (disable, prefer, require, verify-ca,
+	// verify-full) for both postgres and mysql.
+	SSLMode     string `mapstructure:"ssl_mode"`
+	SSLRootCert string `mapstructure:"ssl_root_cert"`
+	Timezone    string
+	// Charset applies to mysql and defaults to utf8mb4.
+	Charset string
+	Sqlite  SqliteConfiguration
+	// Params are appended to the connection string of any driver.
+	Params map[string]string
+
+	// Replicas serve List and Get; empty fields are taken from the primary.
+	Replicas []ReplicaConfiguration
+	// ReplicaPolicy is random or round_robin.
+	ReplicaPolicy string `mapstructure:"replica_policy"`
+	// ReplicaHealthCheck is the ping interval in seconds, 0 disables it.
+	ReplicaHealthCheck int `mapstructure:"replica_health_check"`
+
+	Encryption EncryptionConfiguration
+}
+
+// EncryptionConfiguration holds base64 AES keys by id, or ${VAR}
+// references to them. CurrentKey encrypts new writes and IndexKey signs the
+// blind indexes; unless Enabled personal data is stored in plain text.
+type EncryptionConfiguration struct {
+	Enabled    bool
+	CurrentKey string `mapstructure:"current_key"`
+	Keys       map[string]string
+	IndexKey   string `mapstructure:"index_key"`
+}
+
+type ReplicaConfiguration struct {
+	DSN      string
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+type SqliteConfiguration struct {
+	InMemory    bool   `mapstructure:"in_memory"`
+	JournalMode string `mapstructure:"journal_mode"`
+	// BusyTimeout is in milliseconds.
+	BusyTimeout int `mapstructure:"busy_timeout"`
 }
 
 type ServerConfiguration struct {
 	Port   string
 	Secret string
 	Mode   string
+	// Listen takes addresses such as tcp://:3001 or unix:///run/app.sock;
+	// when empty the server listens on tcp Port.
+	Listen []string
+	// SocketMode is the octal permission set on unix socket files, e.g. "0660".
+	SocketMode string `mapstructure:"socket_mode"`
+
+	GrpcWeb GrpcWebConfiguration `mapstructure:"grpc_web"`
+	Tenancy TenancyConfiguration
+	// RoleClaim is the token claim listing the caller's roles.
+	RoleClaim   string `mapstructure:"role_claim"`
+	Masking     MaskingConfiguration
+	Bulk        BulkConfiguration
+	Watch       WatchConfiguration
+	Idempotency IdempotencyConfiguration
+	// MetricsPort serves expvar metrics on /debug/vars when set, on every
+	// interface and with the command line and memory stats, so it must not
+	// be reachable from outside.
+	MetricsPort string `mapstructure:"metrics_port"`
+
+	// Message sizes are in bytes, timeouts in seconds; zero keeps the grpc default.
+	MaxRecvMsgSize       int    `mapstructure:"max_recv_msg_size"`
+	MaxSendMsgSize       int    `mapstructure:"max_send_msg_size"`
+	MaxConcurrentStreams uint32 `mapstructure:"max_concurrent_streams"`
+	ConnectionTimeout    int    `mapstructure:"connection_timeout"`
+	// CompressionLevel tunes the gzip compressor, from 1 (speed) to 9 (size).
+	CompressionLevel int `mapstructure:"compression_level"`
+	Keepalive        KeepaliveConfiguration
+}
+
+// TenancyConfiguration resolves the caller's tenant from the Claim of the
+// bearer token signed with Secret; Header is only read when there is no
+// Secret.
+type TenancyConfiguration struct {
+	Required bool
+	Claim    string
+	Header   string
+}
+
+// MaskingConfiguration hides personal fields from callers without one of
+// the field's roles; RevealRoles may read a user unmasked through RevealUser.
+type MaskingConfiguration struct {
+	Fields      map[string]MaskFieldConfiguration
+	RevealRoles []string `mapstructure:"reveal_roles"`
+}
+
+// BulkConfiguration tunes BulkUpsertUsers; BatchSize users are written per
+// transaction.
+type BulkConfiguration struct {
+	BatchSize int `mapstructure:"batch_size"`
+}
+
+// WatchConfiguration tunes WatchUsers; times are in seconds. Settle is how
+// long a change waits before watchers see it, and must outlast the longest
+// transaction writing users.
+type WatchConfiguration struct {
+	PollInterval int `mapstructure:"poll_interval"`
+	BatchSize    int `mapstructure:"batch_size"`
+	Settle       int
+}
+
+// IdempotencyConfiguration tunes the idempotency keys of Insert, Update and
+// Delete, in seconds: TTL keeps a response for retries and Lease bounds how
+// long a request running holds its key.
+type IdempotencyConfiguration struct {
+	TTL   int
+	Lease int
+}
+
+type MaskFieldConfiguration struct {
+	// Visible is how many trailing characters stay readable.
+	Visible int
+	Roles   []string
+}
+
+// KeepaliveConfiguration values are in seconds.
+type KeepaliveConfiguration struct {
+	Time                  int
+	Timeout               int
+	MaxConnectionIdle     int  `mapstructure:"max_connection_idle"`
+	MaxConnectionAge      int  `mapstructure:"max_connection_age"`
+	MaxConnectionAgeGrace int  `mapstructure:"max_connection_age_grace"`
+	MinTime               int  `mapstructure:"min_time"`
+	PermitWithoutStream   bool `mapstructure:"permit_without_stream"`
+}
+
+type GrpcWebConfiguration struct {
+	Enabled bool
+	// Port is used for grpc-web when Multiplex is false; otherwise grpc-web
+	// shares Server.Port with native gRPC through h2c, which loses the
+	// keepalive pings and connection age limits of gRPC's own transport.
+	// An empty AllowedOrigins allows none; "*" allows every origin.
+	Port           string
+	Multiplex      bool
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
 }
 
 // SetupDB initialize configuration
@@ -1,10 +1,18 @@
 package config
 
 import (
+	"context"
+	"database/sql/driver"
+	"errors"
 	"fmt"
-	"template-grpc/internal/domain/entity"
+	"log"
+	"net"
+	"template-grpc/internal/infra/database"
+	migrate "template-grpc/internal/infra/migration"
 	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgconn"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -13,8 +21,8 @@ import (
 )
 
 var (
-	DB  *gorm.DB
-	err error
+	DB      *gorm.DB
+	Cluster *database.Cluster
 )
 
 type Database struct {
@@ -22,53 +30,226 @@ type Database struct {
 }
 
 // SetupDB opens a database and saves the reference to `Database` struct.
-func setupDB(configuration *Configuration) {
-	var db = DB
-
-	driver := configuration.Database.Driver
-	database := configuration.Database.Dbname
-	username := configuration.Database.Username
-	password := configuration.Database.Password
-	host := configuration.Database.Host
-	port := configuration.Database.Port
-
-	if driver == "sqlite" { // SQLITE
-		db, err = gorm.Open(sqlite.Open(database+".db"), &gorm.Config{})
+// Connection attempts are retried with exponential backoff until
+// connect_timeout runs out; configuration errors fail straight away.
+func setupDB(configuration *Configuration) error {
+	keyring, err := newKeyring(configuration.Database.Encryption)
+	if err != nil {
+		return err
+	}
+	database.SetKeyring(keyring)
+
+	dialector, gormConfig, err := dialector(configuration.Database)
+	if err != nil {
+		return err
+	}
+
+	db, err := connect(configuration.Database, dialector, gormConfig)
+	if err != nil {
+		return err
+	}
+
+	if err := configurePool(db, configuration.Database); err != nil {
+		return err
+	}
+
+	policy := database.Policy(configuration.Database.ReplicaPolicy)
+	switch policy {
+	case "":
+		policy = database.Random
+	case database.Random, database.RoundRobin:
+	default:
+		return fmt.Errorf("unknown replica_policy %q, expected random or round_robin", policy)
+	}
+	replicas, err := openReplicas(configuration.Database)
+	if err != nil {
+		return err
+	}
+
+	DB = db
+	Cluster = database.NewCluster(db, replicas, policy)
+	Cluster.Watch(time.Duration(configuration.Database.ReplicaHealthCheck) * time.Second)
+	return nil
+}
+
+func configurePool(db *gorm.DB, conf DatabaseConfiguration) error {
+	// Every query on a table with tenant_id is scoped to the caller's tenant.
+	if err := db.Use(database.Tenancy{}); err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	// Change this to true if you want to see SQL queries
+
+	db.Logger.LogMode(logger.Info)
+	sqlDB.SetMaxIdleConns(conf.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(conf.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(conf.MaxLifetime) * time.Second)
+	return nil
+}
+
+// openReplicas connects every read replica once. A replica that cannot be
+// reached at startup is left out with a warning rather than blocking the
+// primary.
+func openReplicas(primary DatabaseConfiguration) ([]*gorm.DB, error) {
+	var replicas []*gorm.DB
+	for _, replica := range primary.Replicas {
+		conf := primary
+		conf.DSN = replica.DSN
+		conf.Host = defaultString(replica.Host, primary.Host)
+		conf.Port = defaultString(replica.Port, primary.Port)
+		conf.Username = defaultString(replica.Username, primary.Username)
+		conf.Password = defaultString(replica.Password, primary.Password)
+
+		dialector, gormConfig, err := dialector(conf)
 		if err != nil {
-			fmt.Println("db err: ", err)
+			return nil, fmt.Errorf("replica %s: %w", conf.Host, err)
 		}
-	} else if driver == "postgres" { // POSTGRES
-		db, err = gorm.Open(postgres.Open("host="+host+" port="+port+" user="+username+" dbname="+database+"  sslmode=disable password="+password), &gorm.Config{})
-		if err != nil {
-			fmt.Println("db err: ", err)
+		db, err := gorm.Open(dialector, gormConfig)
+		if err == nil {
+			err = ping(db, time.Duration(conf.ConnectTimeout)*time.Second)
 		}
-	} else if driver == "mysql" { // MYSQL
-		db, err = gorm.Open(mysql.Open(username+":"+password+"@tcp("+host+":"+port+")/"+database+"?charset=utf8&parseTime=True&loc=Local"), &gorm.Config{
-			SkipDefaultTransaction: true,
-			PrepareStmt:            true,
-		})
 		if err != nil {
-			fmt.Println("db err: ", err)
+			log.Printf("replica %s unreachable, serving reads without it: %v", conf.Host, err)
+			continue
+		}
+		if err := configurePool(db, conf); err != nil {
+			return nil, err
 		}
+		replicas = append(replicas, db)
 	}
+	return replicas, nil
+}
 
-	sqlDB, _ := db.DB()
-	// Change this to true if you want to see SQL queries
+func dialector(conf DatabaseConfiguration) (gorm.Dialector, *gorm.Config, error) {
+	// Applied to every driver: writes run in a transaction only when asked
+	// for, and statements are prepared once and cached.
+	gormConfig := &gorm.Config{
+		SkipDefaultTransaction: true,
+		PrepareStmt:            true,
+	}
 
-	db.Logger.LogMode(logger.Info)
-	sqlDB.SetMaxIdleConns(configuration.Database.MaxIdleConns)
-	sqlDB.SetMaxOpenConns(configuration.Database.MaxOpenConns)
-	sqlDB.SetConnMaxLifetime(time.Duration(configuration.Database.MaxLifetime) * time.Second)
-	DB = db
-	migration()
+	switch conf.Driver {
+	case "sqlite": // SQLITE
+		dsn := conf.DSN
+		if dsn == "" {
+			var err error
+			if dsn, err = sqliteDSN(conf); err != nil {
+				return nil, nil, err
+			}
+		}
+		return sqlite.Open(dsn), gormConfig, nil
+	case "postgres": // POSTGRES
+		dsn := conf.DSN
+		if dsn == "" {
+			dsn = postgresDSN(conf)
+		}
+		if _, err := pgconn.ParseConfig(dsn); err != nil {
+			return nil, nil, fmt.Errorf("invalid postgres dsn: %w", err)
+		}
+		return postgres.Open(dsn), gormConfig, nil
+	case "mysql": // MYSQL
+		dsn := conf.DSN
+		if dsn == "" {
+			var err error
+			if dsn, err = mysqlDSN(conf); err != nil {
+				return nil, nil, err
+			}
+		}
+		if _, err := mysqldriver.ParseDSN(dsn); err != nil {
+			return nil, nil, fmt.Errorf("invalid mysql dsn: %w", err)
+		}
+		return mysql.Open(dsn), gormConfig, nil
+	}
+	return nil, nil, fmt.Errorf("unknown database driver %q, expected sqlite, postgres or mysql", conf.Driver)
 }
 
-// Auto migrate project models
-func migration() {
-	DB.AutoMigrate(&entity.User{})
+func connect(conf DatabaseConfiguration, dialector gorm.Dialector, gormConfig *gorm.Config) (*gorm.DB, error) {
+	timeout := time.Duration(conf.ConnectTimeout) * time.Second
+	interval := time.Duration(conf.RetryInterval) * time.Second
+	maxInterval := time.Duration(conf.RetryMaxInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if maxInterval < interval {
+		maxInterval = interval
+	}
+	deadline := time.Now().Add(timeout)
 
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(dialector, gormConfig)
+		if err == nil {
+			err = ping(db, timeout)
+		}
+		if err == nil {
+			return db, nil
+		}
+		if !retryable(err) {
+			return nil, err
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return nil, fmt.Errorf("database unreachable after %d attempts: %w", attempt, err)
+		}
+
+		log.Printf("database not ready (attempt %d), retrying in %s: %v", attempt, interval, err)
+		time.Sleep(interval)
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+func ping(db *gorm.DB, timeout time.Duration) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return err
+	}
+	return nil
+}
+
+// retryable reports whether err looks like a database that is still starting
+// or unreachable, rather than a configuration or credentials problem.
+func retryable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// cannot_connect_now, too_many_connections
+		return pgErr.Code == "57P03" || pgErr.Code == "53300"
+	}
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysqldriver.ErrInvalidConn) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// migration applies pending versioned migrations
+func migration(configuration *Configuration) error {
+	migrator, err := migrate.New(DB, configuration.Database.Driver)
+	if err != nil {
+		return err
+	}
+	return migrator.Up()
 }
 
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// GetCluster returns the primary together with its read replicas.
+func GetCluster() *database.Cluster {
+	return Cluster
+}
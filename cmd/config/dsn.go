@@ -0,0 +1,167 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// mysqlTLSConfig prefixes the TLS configs registered for verify-ca and
+// verify-full, one per address so the primary and each replica verify
+// against their own root certificate and host name.
+const mysqlTLSConfig = "config-"
+
+// postgresDSN builds a postgres:// URL; sslmode defaults to disable.
+func postgresDSN(conf DatabaseConfiguration) string {
+	params := url.Values{}
+	params.Set("sslmode", defaultString(conf.SSLMode, "disable"))
+	if conf.SSLRootCert != "" {
+		params.Set("sslrootcert", conf.SSLRootCert)
+	}
+	if conf.Timezone != "" {
+		params.Set("TimeZone", conf.Timezone)
+	}
+	for key, value := range conf.Params {
+		params.Set(key, value)
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(conf.Username, conf.Password),
+		Host:     net.JoinHostPort(conf.Host, conf.Port),
+		Path:     "/" + conf.Dbname,
+		RawQuery: params.Encode(),
+	}
+	return dsn.String()
+}
+
+// mysqlDSN builds a go-sql-driver DSN with utf8mb4 and the local timezone
+// unless configured otherwise. ssl_mode takes the postgres names: disable,
+// prefer, require, verify-ca and verify-full.
+func mysqlDSN(conf DatabaseConfiguration) (string, error) {
+	cfg := mysqldriver.NewConfig()
+	cfg.User = conf.Username
+	cfg.Passwd = conf.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(conf.Host, conf.Port)
+	cfg.DBName = conf.Dbname
+	cfg.ParseTime = true
+	cfg.Loc = time.Local
+	if conf.Timezone != "" {
+		loc, err := time.LoadLocation(conf.Timezone)
+		if err != nil {
+			return "", err
+		}
+		cfg.Loc = loc
+	}
+	cfg.Params = map[string]string{"charset": defaultString(conf.Charset, "utf8mb4")}
+	for key, value := range conf.Params {
+		cfg.Params[key] = value
+	}
+
+	switch conf.SSLMode {
+	case "", "disable":
+	case "prefer":
+		cfg.TLSConfig = "preferred"
+	case "require":
+		cfg.TLSConfig = "skip-verify"
+		if conf.SSLRootCert != "" {
+			return "", errors.New("ssl_root_cert needs ssl_mode verify-ca or verify-full")
+		}
+	case "verify-ca", "verify-full":
+		tlsConfig, err := verifyTLSConfig(conf.SSLRootCert, conf.Host, conf.SSLMode == "verify-full")
+		if err != nil {
+			return "", err
+		}
+		name := mysqlTLSConfig + cfg.Addr
+		if err := mysqldriver.RegisterTLSConfig(name, tlsConfig); err != nil {
+			return "", err
+		}
+		cfg.TLSConfig = name
+	default:
+		return "", fmt.Errorf("unknown ssl_mode %q", conf.SSLMode)
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// sqliteDSN keeps the historical "<dbname>.db" file name when dbname has no
+// extension, and supports shared in-memory databases.
+func sqliteDSN(conf DatabaseConfiguration) (string, error) {
+	params := url.Values{}
+	if conf.Sqlite.JournalMode != "" {
+		params.Set("_journal_mode", conf.Sqlite.JournalMode)
+	}
+	if conf.Sqlite.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.Itoa(conf.Sqlite.BusyTimeout))
+	}
+	for key, value := range conf.Params {
+		params.Set(key, value)
+	}
+
+	name := conf.Dbname
+	if conf.Sqlite.InMemory {
+		name = defaultString(name, "memdb")
+		params.Set("mode", "memory")
+		params.Set("cache", "shared")
+	} else {
+		if name == "" {
+			return "", errors.New("sqlite needs a dbname")
+		}
+		if filepath.Ext(name) == "" {
+			name += ".db"
+		}
+	}
+	if len(params) == 0 {
+		return "file:" + name, nil
+	}
+	return "file:" + name + "?" + params.Encode(), nil
+}
+
+func verifyTLSConfig(rootCert, host string, verifyHost bool) (*tls.Config, error) {
+	var roots *x509.CertPool
+	if rootCert != "" {
+		pem, err := os.ReadFile(rootCert)
+		if err != nil {
+			return nil, err
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", rootCert)
+		}
+	}
+	if verifyHost {
+		return &tls.Config{RootCAs: roots, ServerName: host}, nil
+	}
+
+	// verify-ca checks the chain but not the host name.
+	return &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("server sent no certificate")
+			}
+			opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+			for _, cert := range state.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := state.PeerCertificates[0].Verify(opts)
+			return err
+		},
+	}, nil
+}
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// rootCert writes a self-signed CA certificate and returns its path.
+func rootCert(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPostgresDSN(t *testing.T) {
+	base := DatabaseConfiguration{Username: "app", Password: "p@ss", Host: "db", Port: "5432", Dbname: "usuarios"}
+	tests := []struct {
+		name string
+		edit func(conf *DatabaseConfiguration)
+		want string
+	}{
+		{"defaults", func(*DatabaseConfiguration) {}, "postgres://app:p%40ss@db:5432/usuarios?sslmode=disable"},
+		{"verify full", func(conf *DatabaseConfiguration) {
+			conf.SSLMode, conf.SSLRootCert = "verify-full", "/etc/ca.pem"
+		}, "postgres://app:p%40ss@db:5432/usuarios?sslmode=verify-full&sslrootcert=%2Fetc%2Fca.pem"},
+		{"timezone and params", func(conf *DatabaseConfiguration) {
+			conf.Timezone = "UTC"
+			conf.Params = map[string]string{"application_name": "usuario"}
+		}, "postgres://app:p%40ss@db:5432/usuarios?TimeZone=UTC&application_name=usuario&sslmode=disable"},
+		{"ipv6 host", func(conf *DatabaseConfiguration) { conf.Host = "::1" }, "postgres://app:p%40ss@[::1]:5432/usuarios?sslmode=disable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := base
+			tt.edit(&conf)
+			if got := postgresDSN(conf); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMysqlDSN(t *testing.T) {
+	ca := rootCert(t)
+	base := DatabaseConfiguration{Username: "app", Password: "secret", Host: "db", Port: "3306", Dbname: "usuarios", Timezone: "UTC"}
+	tests := []struct {
+		name    string
+		edit    func(conf *DatabaseConfiguration)
+		tls     string
+		params  map[string]string
+		wantErr string
+	}{
+		{name: "defaults", edit: func(*DatabaseConfiguration) {}, params: map[string]string{"charset": "utf8mb4"}},
+		{name: "charset and params", edit: func(conf *DatabaseConfiguration) {
+			conf.Charset = "latin1"
+			conf.Params = map[string]string{"sql_mode": "'ANSI'"}
+		}, params: map[string]string{"charset": "latin1", "sql_mode": "'ANSI'"}},
+		{name: "prefer", edit: func(conf *DatabaseConfiguration) { conf.SSLMode = "prefer" }, tls: "preferred"},
+		{name: "require", edit: func(conf *DatabaseConfiguration) { conf.SSLMode = "require" }, tls: "skip-verify"},
+		{name: "require with a root cert", edit: func(conf *DatabaseConfiguration) {
+			conf.SSLMode, conf.SSLRootCert = "require", ca
+		}, wantErr: "ssl_root_cert needs"},
+		{name: "verify ca", edit: func(conf *DatabaseConfiguration) {
+			conf.SSLMode, conf.SSLRootCert = "verify-ca", ca
+		}, tls: "config-db:3306"},
+		{name: "verify full on a replica", edit: func(conf *DatabaseConfiguration) {
+			conf.SSLMode, conf.SSLRootCert, conf.Host = "verify-full", ca, "replica"
+		}, tls: "config-replica:3306"},
+		{name: "missing root cert", edit: func(conf *DatabaseConfiguration) {
+			conf.SSLMode, conf.SSLRootCert = "verify-full", filepath.Join(t.TempDir(), "none.pem")
+		}, wantErr: "no such file"},
+		{name: "unknown ssl mode", edit: func(conf *DatabaseConfiguration) { conf.SSLMode = "allow" }, wantErr: `unknown ssl_mode "allow"`},
+		{name: "unknown timezone", edit: func(conf *DatabaseConfiguration) { conf.Timezone = "Mars/Olympus" }, wantErr: "unknown time zone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := base
+			tt.edit(&conf)
+			dsn, err := mysqlDSN(conf)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %q, %v, want an error with %q", dsn, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := mysqldriver.ParseDSN(dsn)
+			if err != nil {
+				t.Fatalf("%s: %v", dsn, err)
+			}
+			if parsed.User != "app" || parsed.Passwd != "secret" || parsed.Addr != conf.Host+":"+conf.Port || parsed.DBName != "usuarios" || !parsed.ParseTime || parsed.Loc.String() != "UTC" {
+				t.Fatalf("parsed %+v", parsed)
+			}
+			if parsed.TLSConfig != tt.tls {
+				t.Fatalf("tls = %q, want %q", parsed.TLSConfig, tt.tls)
+			}
+			for key, value := range tt.params {
+				if parsed.Params[key] != value {
+					t.Fatalf("param %s = %q, want %q", key, parsed.Params[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyTLSConfig(t *testing.T) {
+	ca := rootCert(t)
+	full, err := verifyTLSConfig(ca, "db", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.ServerName != "db" || full.InsecureSkipVerify || full.RootCAs == nil {
+		t.Fatalf("verify-full: %+v", full)
+	}
+	chain, err := verifyTLSConfig(ca, "db", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// verify-ca skips the host name but still checks the chain.
+	if !chain.InsecureSkipVerify || chain.VerifyConnection == nil {
+		t.Fatalf("verify-ca: %+v", chain)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("no pem here"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyTLSConfig(empty, "db", true); err == nil || !strings.Contains(err.Error(), "no certificates") {
+		t.Fatalf("a file without certificates: %v", err)
+	}
+}
+
+func TestSqliteDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    DatabaseConfiguration
+		want    string
+		wantErr bool
+	}{
+		{"adds the extension", DatabaseConfiguration{Dbname: "usuarios"}, "file:usuarios.db", false},
+		{"keeps an extension", DatabaseConfiguration{Dbname: "data/usuarios.sqlite"}, "file:data/usuarios.sqlite", false},
+		{"pragmas", DatabaseConfiguration{Dbname: "usuarios", Sqlite: SqliteConfiguration{JournalMode: "WAL", BusyTimeout: 5000}}, "file:usuarios.db?_busy_timeout=5000&_journal_mode=WAL", false},
+		{"in memory", DatabaseConfiguration{Sqlite: SqliteConfiguration{InMemory: true}}, "file:memdb?cache=shared&mode=memory", false},
+		{"no dbname", DatabaseConfiguration{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sqliteDSN(tt.conf)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	repository "template-grpc/internal/domain/repository/implement/user"
+	"template-grpc/internal/infra/database"
+
+	"gorm.io/gorm"
+)
+
+const reencryptBatch = 500
+
+// newKeyring decodes the configured keys, expanding ${VAR} references so
+// they can come from the environment. The index key is always required;
+// the AES keys only when encryption is enabled. Disabled, the keys that are
+// set still decrypt, so `reencrypt` writes the rows back in plain text.
+func newKeyring(conf EncryptionConfiguration) (*database.Keyring, error) {
+	indexKey, err := decodeKey(conf.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption index_key: %w", err)
+	}
+
+	current := ""
+	if conf.Enabled {
+		if conf.CurrentKey == "" {
+			return nil, errors.New("encryption is enabled without a current_key")
+		}
+		current = conf.CurrentKey
+	}
+	keys := make(map[string][]byte, len(conf.Keys))
+	for id, value := range conf.Keys {
+		key, err := decodeKey(value)
+		if err != nil && !conf.Enabled {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return database.NewKeyring(current, keys, indexKey)
+}
+
+func decodeKey(value string) ([]byte, error) {
+	value = os.ExpandEnv(value)
+	if value == "" {
+		return nil, errors.New("not set")
+	}
+	return base64.StdEncoding.DecodeString(value)
+}
+
+// Reencrypt runs the reencrypt command: it rewrites every user, deleted or
+// not, with the current key and recomputes its blind and search indexes.
+// Run it after rotating keys, before removing the old ones, to encrypt rows
+// written before encryption was enabled, or after migrations adding indexes;
+// the service won't start while users lack them. With encryption disabled it
+// decrypts instead, which rolling back 0006_users_document_hash needs first.
+func Reencrypt(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: reencrypt")
+	}
+	conf := GetConfig()
+	if conf.Database.Driver == MemoryDriver {
+		return fmt.Errorf("the %s driver keeps nothing at rest", MemoryDriver)
+	}
+	if err := setupDB(conf); err != nil {
+		return err
+	}
+
+	// Tenancy scopes every query, so walk the tenants one at a time.
+	var tenants []string
+	if err := GetDB().Raw("SELECT DISTINCT tenant_id FROM users").Scan(&tenants).Error; err != nil {
+		return err
+	}
+	users, events := 0, 0
+	for _, tenant := range tenants {
+		ctx := identity.NewContext(context.Background(), identity.Identity{Actor: "reencrypt", Tenant: tenant})
+		n, err := reencryptUsers(GetDB().WithContext(ctx))
+		users += n
+		if err != nil {
+			return err
+		}
+		n, err = reencryptAuditLog(GetDB().WithContext(ctx))
+		events += n
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("reencrypted %d users and %d audit events\n", users, events)
+	return nil
+}
+
+// unindexed counts the users, deleted or not and of every tenant, missing a
+// blind index: rows written before migrations that add one, which can't be
+// found or kept unique until `reencrypt` computes it. The migration adding
+// phone_hash leaves name_search and the search tokens to reencrypt too,
+// since SQL can't fold accents the way entity.NormalizeName does.
+func unindexed(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Raw("SELECT COUNT(*) FROM users WHERE document_hash IS NULL OR phone_hash IS NULL").Scan(&count).Error
+	return count, err
+}
+
+func reencryptUsers(db *gorm.DB) (int, error) {
+	total := 0
+	var batch []entity.User
+	result := db.Unscoped().FindInBatches(&batch, reencryptBatch, func(*gorm.DB, int) error {
+		for _, user := range batch {
+			if err := repository.Index(&user); err != nil {
+				return err
+			}
+			err := db.Unscoped().Model(&user).
+				Select("document", "document_hash", "phone", "phone_hash", "name_search").
+				Updates(&user).Error
+			if err != nil {
+				return err
+			}
+			if err := repository.SaveSearchTokens(db, user); err != nil {
+				return err
+			}
+		}
+		total += len(batch)
+		return nil
+	})
+	return total, result.Error
+}
+
+func reencryptAuditLog(db *gorm.DB) (int, error) {
+	total := 0
+	var batch []entity.AuditEvent
+	result := db.FindInBatches(&batch, reencryptBatch, func(*gorm.DB, int) error {
+		for _, event := range batch {
+			if err := db.Model(&event).Select("changes").Updates(&event).Error; err != nil {
+				return err
+			}
+		}
+		total += len(batch)
+		return nil
+	})
+	return total, result.Error
+}
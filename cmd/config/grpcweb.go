@@ -0,0 +1,79 @@
+package config
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// NewGrpcWebServer serves the gRPC server over HTTP as grpc-web and
+// grpc-web-text, so browsers can call it without a proxy. With Multiplex
+// enabled native gRPC requests are served too, over h2c.
+//
+// HTTP connections are not gRPC's own, so the server's limits are copied
+// onto them: max_concurrent_streams, connection_timeout to read the request
+// headers and max_connection_idle. net/http can't send keepalive pings nor
+// age connections out, so multiplexed gRPC goes without those.
+func NewGrpcWebServer(s *grpc.Server, conf ServerConfiguration) *http.Server {
+	web := conf.GrpcWeb
+	idle := seconds(conf.Keepalive.MaxConnectionIdle)
+	srv := &http.Server{
+		Handler:           grpcWebHandler(s, web),
+		ReadHeaderTimeout: seconds(conf.ConnectionTimeout),
+		IdleTimeout:       idle,
+	}
+	if !web.Multiplex {
+		return srv
+	}
+
+	if ka := conf.Keepalive; ka.MaxConnectionAge > 0 || ka.Time > 0 {
+		log.Printf("grpc-web: multiplexed gRPC connections are neither pinged nor aged out")
+	}
+	srv.Handler = h2c.NewHandler(srv.Handler, &http2.Server{
+		MaxConcurrentStreams: conf.MaxConcurrentStreams,
+		IdleTimeout:          idle,
+	})
+	return srv
+}
+
+func grpcWebHandler(s *grpc.Server, conf GrpcWebConfiguration) http.Handler {
+	wrapped := grpcweb.WrapServer(s,
+		grpcweb.WithOriginFunc(allowedOrigin(conf.AllowedOrigins)),
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wrapped.IsGrpcWebRequest(r) || wrapped.IsAcceptableGrpcCorsRequest(r) {
+			wrapped.ServeHTTP(w, r)
+			return
+		}
+		if conf.Multiplex && isGrpcRequest(r) {
+			s.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "unsupported protocol", http.StatusUnsupportedMediaType)
+	})
+}
+
+func isGrpcRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// allowedOrigin allows the listed origins, every one with "*" and none
+// when the list is empty.
+func allowedOrigin(origins []string) func(string) bool {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		if origin == "*" {
+			return func(string) bool { return true }
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+	return func(origin string) bool {
+		return allowed[strings.TrimSuffix(origin, "/")]
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestAllowedOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		want    bool
+	}{
+		{"empty list denies", nil, "http://localhost:3000", false},
+		{"listed", []string{"http://localhost:3000"}, "http://localhost:3000", true},
+		{"trailing slash", []string{"http://localhost:3000/"}, "http://localhost:3000", true},
+		{"trailing slash on the request", []string{"http://localhost:3000"}, "http://localhost:3000/", true},
+		{"other port", []string{"http://localhost:3000"}, "http://localhost:3001", false},
+		{"other scheme", []string{"http://localhost:3000"}, "https://localhost:3000", false},
+		{"no origin", []string{"http://localhost:3000"}, "", false},
+		{"wildcard", []string{"http://localhost:3000", "*"}, "https://example.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowedOrigin(tt.origins)(tt.origin); got != tt.want {
+				t.Fatalf("allowedOrigin(%q)(%q) = %v, want %v", tt.origins, tt.origin, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Listen opens every address in the server configuration. Unix socket files
+// left behind by a previous run are replaced, and they are removed again when
+// the listener is closed.
+func Listen(conf ServerConfiguration) ([]net.Listener, error) {
+	addresses := conf.Listen
+	if len(addresses) == 0 {
+		addresses = []string{"tcp://:" + conf.Port}
+	}
+
+	var listeners []net.Listener
+	for _, address := range addresses {
+		listener, err := listen(address, conf.SocketMode)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("listen %s: %w", address, err)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+func listen(address, socketMode string) (net.Listener, error) {
+	network, addr, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if network != "unix" {
+		return net.Listen(network, addr)
+	}
+
+	if err := removeStaleSocket(addr); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if socketMode != "" {
+		mode, err := strconv.ParseUint(socketMode, 8, 32)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("invalid socket_mode %q", socketMode)
+		}
+		if err := os.Chmod(addr, os.FileMode(mode)); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+	return listener, nil
+}
+
+func parseAddress(address string) (network, addr string, err error) {
+	scheme, rest, found := strings.Cut(address, "://")
+	if !found {
+		return "tcp", address, nil
+	}
+	switch scheme {
+	case "tcp", "tcp4", "tcp6", "unix":
+		if rest == "" {
+			return "", "", errors.New("empty address")
+		}
+		return scheme, rest, nil
+	}
+	return "", "", fmt.Errorf("unsupported scheme %q", scheme)
+}
+
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		addr    string
+		wantErr string
+	}{
+		{":3001", "tcp", ":3001", ""},
+		{"tcp://:3001", "tcp", ":3001", ""},
+		{"tcp6://[::1]:3001", "tcp6", "[::1]:3001", ""},
+		{"unix:///run/usuario/grpc.sock", "unix", "/run/usuario/grpc.sock", ""},
+		{"unix://", "", "", "empty address"},
+		{"http://:3001", "", "", `unsupported scheme "http"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			network, addr, err := parseAddress(tt.address)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || network != tt.network || addr != tt.addr {
+				t.Fatalf("got %s %s, %v, want %s %s", network, addr, err, tt.network, tt.addr)
+			}
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "stale.sock")
+	if l, err := net.Listen("unix", stale); err != nil {
+		t.Fatal(err)
+	} else {
+		// Leave the file behind, as a crashed run would.
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		address    string
+		socketMode string
+		mode       os.FileMode
+		wantErr    string
+	}{
+		{name: "tcp", address: "tcp://127.0.0.1:0"},
+		{name: "unix with a mode", address: "unix://" + filepath.Join(dir, "a.sock"), socketMode: "0600", mode: 0o600},
+		{name: "unix replacing a stale socket", address: "unix://" + stale, socketMode: "0660", mode: 0o660},
+		{name: "unix over a file", address: "unix://" + file, wantErr: "is not a socket"},
+		{name: "invalid socket mode", address: "unix://" + filepath.Join(dir, "b.sock"), socketMode: "rw", wantErr: `invalid socket_mode "rw"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := listen(tt.address, tt.socketMode)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer listener.Close()
+			if tt.mode == 0 {
+				return
+			}
+			path := strings.TrimPrefix(tt.address, "unix://")
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != tt.mode {
+				t.Fatalf("%s has mode %s, want a socket with %s", path, info.Mode(), tt.mode)
+			}
+			listener.Close()
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Fatalf("closing left %s behind: %v", path, err)
+			}
+		})
+	}
+}
+
+func TestListenClosesOnFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	_, err := Listen(ServerConfiguration{Listen: []string{"unix://" + path, "ftp://:21"}})
+	if err == nil || !strings.Contains(err.Error(), "listen ftp://:21") {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("the socket opened before the failure was left open: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"template-grpc/internal/infra/outbox"
+)
+
+// StartOutbox runs the outbox relay in the background when enabled. The
+// returned stop waits for the batch in flight and closes the publisher.
+func StartOutbox(conf *Configuration) (stop func(), err error) {
+	if !conf.Outbox.Enabled || conf.Database.Driver == MemoryDriver {
+		return func() {}, nil
+	}
+	publisher, err := newPublisher(conf.Outbox)
+	if err != nil {
+		return nil, err
+	}
+
+	relay := outbox.NewRelay(GetDB(), publisher, outbox.Options{
+		Interval:         seconds(conf.Outbox.Interval),
+		BatchSize:        conf.Outbox.BatchSize,
+		MaxAttempts:      conf.Outbox.MaxAttempts,
+		RetryInterval:    seconds(conf.Outbox.RetryInterval),
+		MaxRetryInterval: seconds(conf.Outbox.MaxRetryInterval),
+		Retention:        seconds(conf.Outbox.Retention),
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		relay.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+		publisher.Close()
+	}, nil
+}
+
+func newPublisher(conf OutboxConfiguration) (outbox.Publisher, error) {
+	switch conf.Publisher {
+	case "":
+		return nil, fmt.Errorf("the outbox needs a publisher: log, file, nats or kafka")
+	case "log":
+		return outbox.NewLogPublisher(), nil
+	case "file":
+		return outbox.NewFilePublisher(defaultString(conf.File, "outbox.jsonl"))
+	case "nats":
+		return outbox.NewNatsPublisher(conf.Nats.URL, conf.Nats.SubjectPrefix)
+	case "kafka":
+		if len(conf.Kafka.Brokers) == 0 {
+			return nil, fmt.Errorf("the kafka publisher needs brokers")
+		}
+		return outbox.NewKafkaPublisher(conf.Kafka.Brokers, conf.Kafka.TopicPrefix), nil
+	}
+	return nil, fmt.Errorf("unknown outbox publisher %q, expected log, file, nats or kafka", conf.Publisher)
+}
+
+// Outbox runs the outbox command: requeue gives dead lettered events a new
+// set of attempts.
+func Outbox(args []string) error {
+	if len(args) != 1 || args[0] != "requeue" {
+		return fmt.Errorf("usage: outbox requeue")
+	}
+	conf := GetConfig()
+	if conf.Database.Driver == MemoryDriver {
+		return fmt.Errorf("the %s driver has no outbox", MemoryDriver)
+	}
+	if err := setupDB(conf); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	n, err := outbox.Requeue(ctx, GetDB())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("requeued %d events\n", n)
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"template-grpc/internal/infra/seed"
+)
+
+// Seed runs the seed command: seed [-profile name] [-truncate].
+func Seed(args []string) error {
+	conf := GetConfig()
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	dir := flags.String("dir", defaultString(conf.Seed.Dir, "../data/seeds"), "fixtures directory")
+	profile := flags.String("profile", defaultString(conf.Seed.Profile, "dev"), "fixtures profile")
+	truncate := flags.Bool("truncate", false, "delete the seeded tenants' users and their rows first")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if conf.Database.Driver == MemoryDriver {
+		return fmt.Errorf("the %s driver keeps no data to seed", MemoryDriver)
+	}
+	fixtures, err := seed.Load(*dir, *profile)
+	if err != nil {
+		return err
+	}
+	if err := setupDB(conf); err != nil {
+		return err
+	}
+	result, err := seed.Run(context.Background(), GetDB(), fixtures, *truncate)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d inserted, %d updated, %d unchanged\n", *profile, result.Inserted, result.Updated, result.Unchanged)
+	return nil
+}
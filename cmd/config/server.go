@@ -0,0 +1,69 @@
+package config
+
+import (
+	"template-grpc/cmd/handler"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+)
+
+// NewServer creates the gRPC server tuned by the server configuration.
+// Importing the gzip encoding registers it, so clients may compress requests
+// and get compressed responses back.
+func NewServer(conf ServerConfiguration) (*grpc.Server, error) {
+	if conf.CompressionLevel != 0 {
+		if err := gzip.SetLevel(conf.CompressionLevel); err != nil {
+			return nil, err
+		}
+	}
+	return grpc.NewServer(serverOptions(conf)...), nil
+}
+
+func serverOptions(conf ServerConfiguration) []grpc.ServerOption {
+	identity := handler.IdentityOptions{
+		Secret:        conf.Secret,
+		TenantClaim:   conf.Tenancy.Claim,
+		TenantHeader:  conf.Tenancy.Header,
+		RoleClaim:     conf.RoleClaim,
+		RequireTenant: conf.Tenancy.Required,
+	}
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(handler.NewIdentityInterceptor(identity)),
+		grpc.ChainStreamInterceptor(handler.NewStreamIdentityInterceptor(identity)),
+	}
+
+	if conf.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(conf.MaxRecvMsgSize))
+	}
+	if conf.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(conf.MaxSendMsgSize))
+	}
+	if conf.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(conf.MaxConcurrentStreams))
+	}
+	if conf.ConnectionTimeout > 0 {
+		opts = append(opts, grpc.ConnectionTimeout(seconds(conf.ConnectionTimeout)))
+	}
+
+	ka := conf.Keepalive
+	opts = append(opts,
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:                  seconds(ka.Time),
+			Timeout:               seconds(ka.Timeout),
+			MaxConnectionIdle:     seconds(ka.MaxConnectionIdle),
+			MaxConnectionAge:      seconds(ka.MaxConnectionAge),
+			MaxConnectionAgeGrace: seconds(ka.MaxConnectionAgeGrace),
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             seconds(ka.MinTime),
+			PermitWithoutStream: ka.PermitWithoutStream,
+		}),
+	)
+	return opts
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
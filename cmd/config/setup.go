@@ -2,14 +2,30 @@ package config
 
 import (
 	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
 	"template-grpc/cmd/handler"
+	"template-grpc/internal/domain/repository/implement/audit"
+	"template-grpc/internal/domain/repository/implement/cache"
+	"template-grpc/internal/domain/repository/implement/memory"
+	"template-grpc/internal/domain/repository/implement/unitofwork"
 	repository "template-grpc/internal/domain/repository/implement/user"
+	irepository "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
 	pb "template-grpc/internal/infra/proto"
+	"time"
 
+	"github.com/redis/go-redis/v9"
 	"google.golang.org/grpc"
 )
 
 func init() {
+	// Test binaries build the configuration they need themselves.
+	if strings.HasSuffix(os.Args[0], ".test") {
+		return
+	}
 	var configPath = ""
 	configPath = *flag.String("config", "", "")
 
@@ -25,11 +41,99 @@ func setConfiguration(configPath string) {
 
 }
 
+// stopWatches ends the watches of the server registered by Run.
+var stopWatches = func() {}
+
 func Run(s *grpc.Server, configPath string) *grpc.Server {
 
 	conf := GetConfig()
-	setupDB(conf)
-	pb.RegisterUserCrudServer(s, handler.NewServerUser(repository.NewRepository()))
+	usercrud, unitOfWork, auditLog, feed, idempotency := repositories(conf)
+	usercrud, unitOfWork, err := cached(usercrud, unitOfWork, conf.Cache)
+	if err != nil {
+		log.Fatalf("No se pudo configurar la caché, %v", err)
+	}
+	server := handler.NewServerUser(usercrud, unitOfWork, auditLog, feed, idempotency, handler.ServerOptions{
+		Masking: maskingPolicy(conf.Server.Masking),
+		Bulk:    handler.BulkOptions{BatchSize: conf.Server.Bulk.BatchSize},
+		Watch: handler.WatchOptions{
+			PollInterval: seconds(conf.Server.Watch.PollInterval),
+			BatchSize:    conf.Server.Watch.BatchSize,
+		},
+		Idempotency: handler.IdempotencyOptions{
+			TTL:   seconds(conf.Server.Idempotency.TTL),
+			Lease: seconds(conf.Server.Idempotency.Lease),
+		},
+	})
+	pb.RegisterUserCrudServer(s, server)
+	stopWatches = server.StopWatches
 	return s
 
 }
+
+// StopWatches ends the WatchUsers streams, which would otherwise keep a
+// graceful stop waiting; clients resume them elsewhere.
+func StopWatches() {
+	stopWatches()
+}
+
+func maskingPolicy(conf MaskingConfiguration) handler.MaskingPolicy {
+	policy := handler.MaskingPolicy{
+		Fields:      make(map[string]handler.MaskRule, len(conf.Fields)),
+		RevealRoles: conf.RevealRoles,
+	}
+	for field, rule := range conf.Fields {
+		policy.Fields[field] = handler.MaskRule{Visible: rule.Visible, Roles: rule.Roles}
+	}
+	return policy
+}
+
+// repositories keeps data in process for the memory driver and in the
+// configured database otherwise.
+func repositories(conf *Configuration) (irepository.IUserCrud, irepository.IUnitOfWork, irepository.IAuditLog, irepository.IUserFeed, irepository.IIdempotencyStore) {
+	if conf.Database.Driver == MemoryDriver {
+		auditLog := memory.NewAuditLog()
+		usercrud := memory.NewRepository(auditLog)
+		return usercrud, memory.NewUnitOfWork(usercrud), auditLog, memory.NewFeed(usercrud), memory.NewIdempotencyStore()
+	}
+
+	if err := setupDB(conf); err != nil {
+		log.Fatalf("No se pudo conectar a la base de datos, %v", err)
+	}
+	if conf.Database.AutoMigrate {
+		if err := migration(conf); err != nil {
+			log.Fatalf("Error al aplicar las migraciones, %v", err)
+		}
+	}
+	if count, err := unindexed(GetDB()); err != nil {
+		log.Fatalf("No se pudo comprobar el índice de documentos, %v", err)
+	} else if count > 0 {
+		log.Fatalf("Hay %d usuarios sin índice de documento; ejecuta reencrypt antes de arrancar", count)
+	}
+	cluster := GetCluster()
+	feed := repository.NewFeed(cluster, seconds(conf.Server.Watch.Settle))
+	unitOfWork := unitofwork.NewUnitOfWork(cluster, database.DefaultRetries)
+	return repository.NewRepository(cluster), unitOfWork, audit.NewRepository(cluster), feed, repository.NewIdempotencyStore(cluster)
+}
+
+// cached wraps usercrud and the repositories of unitOfWork with the
+// configured cache.
+func cached(usercrud irepository.IUserCrud, unitOfWork irepository.IUnitOfWork, conf CacheConfiguration) (irepository.IUserCrud, irepository.IUnitOfWork, error) {
+	ttl := time.Duration(conf.TTL) * time.Second
+	var store cache.Store
+	switch conf.Driver {
+	case "", "none":
+		return usercrud, unitOfWork, nil
+	case "memory":
+		store = cache.NewLRU(conf.Size)
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     conf.Redis.Addr,
+			Password: conf.Redis.Password,
+			DB:       conf.Redis.DB,
+		})
+		store = cache.NewRedis(client, conf.Redis.Prefix)
+	default:
+		return nil, nil, fmt.Errorf("unknown cache driver %q, expected none, memory or redis", conf.Driver)
+	}
+	return cache.NewRepository(usercrud, store, ttl), cache.NewUnitOfWork(unitOfWork, store, ttl), nil
+}
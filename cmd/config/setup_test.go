@@ -0,0 +1,22 @@
+package config
+
+import (
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// TestShutdownWithMemoryDriver runs the shutdown of main against the memory
+// driver, which never opens a database.
+func TestShutdownWithMemoryDriver(t *testing.T) {
+	previous := Config
+	Config = &Configuration{Database: DatabaseConfiguration{Driver: MemoryDriver}}
+	t.Cleanup(func() { Config = previous })
+
+	s := Run(grpc.NewServer(), "")
+	StopWatches()
+	s.GracefulStop()
+	if err := GetCluster().Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	irepository "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+
+	pb "template-grpc/internal/infra/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func (s *server) ListAuditEvents(ctx context.Context, req *pb.ListAuditEventsRequest) (*pb.AuditEvents, error) {
+	filter := irepository.AuditFilter{
+		Entity:   req.Entity,
+		EntityID: req.EntityId,
+		Actor:    req.Actor,
+		Offset:   int(req.Offset),
+		Limit:    int(req.Limit),
+	}
+	if req.From != nil {
+		filter.From = req.From.AsTime()
+	}
+	if req.To != nil {
+		filter.To = req.To.AsTime()
+	}
+
+	events, res := s.auditLog.List(readContext(ctx), filter)
+	if !res.IsOk {
+		return nil, toError(res)
+	}
+	out := &pb.AuditEvents{Events: make([]*pb.AuditEvent, 0, len(events))}
+	for _, event := range events {
+		pbEvent, err := toAuditEvent(event)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		for _, change := range pbEvent.Changes {
+			s.masking.change(ctx, change)
+		}
+		out.Events = append(out.Events, pbEvent)
+	}
+	return out, nil
+}
+
+func toAuditEvent(event entity.AuditEvent) (*pb.AuditEvent, error) {
+	changes, err := event.FieldChanges()
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	out := &pb.AuditEvent{
+		Id:        event.ID,
+		Entity:    event.Entity,
+		EntityId:  event.EntityID,
+		Action:    event.Action,
+		Actor:     event.Actor,
+		CreatedAt: timestamp(event.CreatedAt),
+		Reason:    event.Reason,
+	}
+	for _, field := range fields {
+		before, err := structpb.NewValue(changes[field].Before)
+		if err != nil {
+			return nil, err
+		}
+		after, err := structpb.NewValue(changes[field].After)
+		if err != nil {
+			return nil, err
+		}
+		out.Changes = append(out.Changes, &pb.FieldChange{Field: field, Before: before, After: after})
+	}
+	return out, nil
+}
+
+// RevealUser returns a user unmasked to callers holding a reveal role, and
+// records who read it and why before answering.
+func (s *server) RevealUser(ctx context.Context, req *pb.RevealUserRequest) (*pb.User, error) {
+	if !s.masking.canReveal(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "no tiene permiso para ver los datos completos")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, status.Error(codes.InvalidArgument, "el motivo es requerido")
+	}
+
+	user, res := s.userCrud.Get(database.WithPrimary(ctx), req.Id)
+	if !res.IsOk {
+		return nil, toError(res)
+	}
+	event, err := entity.NewAuditEvent("users", user.ID, entity.ActionReveal, identity.Actor(ctx), nil, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	event.Reason = req.Reason
+	if err := s.auditLog.Record(ctx, event); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	setETag(ctx, res)
+	return toProto(user), nil
+}
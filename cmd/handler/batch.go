@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"context"
+	"template-grpc/internal/domain/entity"
+
+	pb "template-grpc/internal/infra/proto"
+)
+
+// BatchGetUsers reads every id in one query and answers them in the order
+// asked, marking those not found instead of failing the batch.
+func (s *server) BatchGetUsers(ctx context.Context, req *pb.BatchGetUsersRequest) (*pb.BatchGetUsersResponse, error) {
+	if err := readMask(req.ReadMask); err != nil {
+		return nil, err
+	}
+	users, res := s.userCrud.GetMany(readContext(ctx), req.Ids)
+	if !res.IsOk {
+		return nil, toError(res)
+	}
+	found := make(map[uint64]entity.User, len(users))
+	for _, user := range users {
+		found[user.ID] = user
+	}
+
+	out := &pb.BatchGetUsersResponse{Results: make([]*pb.BatchGetResult, 0, len(req.Ids))}
+	for _, id := range req.Ids {
+		result := &pb.BatchGetResult{Id: id}
+		if user, ok := found[id]; ok {
+			result.Found = true
+			result.User = trim(s.masking.user(ctx, toProto(user)), req.ReadMask)
+		}
+		out.Results = append(out.Results, result)
+	}
+	return out, nil
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"template-grpc/internal/domain/entity"
+	objectvalue "template-grpc/internal/domain/object-value"
+
+	pb "template-grpc/internal/infra/proto"
+
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultBulkBatchSize is used when BulkOptions leave BatchSize unset.
+const DefaultBulkBatchSize = 500
+
+// BulkOptions tune BulkUpsertUsers: BatchSize users are written per
+// transaction.
+type BulkOptions struct {
+	BatchSize int
+}
+
+// BulkUpsertUsers validates each user as it arrives and upserts the valid
+// ones a batch at a time. Batches commit as they fill, so those written
+// before the client gives up stay written.
+func (s *server) BulkUpsertUsers(stream pb.UserCrud_BulkUpsertUsersServer) error {
+	ctx := stream.Context()
+	batchSize := s.bulk.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBulkBatchSize
+	}
+
+	out := &pb.BulkUpsertUsersResponse{}
+	var (
+		batch   []entity.User
+		indexes []int32
+	)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		for i, res := range s.userCrud.Upsert(ctx, batch) {
+			out.Results = append(out.Results, bulkResult(indexes[i], res))
+		}
+		batch, indexes = batch[:0], indexes[:0]
+	}
+
+	for index := int32(0); ; index++ {
+		user, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if message := validateUser(user); message != "" {
+			out.Results = append(out.Results, bulkResult(index, objectvalue.Fail(codes.InvalidArgument, message)))
+			continue
+		}
+		user.Id, user.Version = 0, 0
+		batch = append(batch, toEntity(user))
+		indexes = append(indexes, index)
+		if len(batch) == batchSize {
+			flush()
+		}
+	}
+	flush()
+
+	sort.Slice(out.Results, func(i, j int) bool { return out.Results[i].Index < out.Results[j].Index })
+	for _, result := range out.Results {
+		switch {
+		case result.Error != "":
+			out.Failed++
+		case result.Created:
+			out.Created++
+		default:
+			out.Updated++
+		}
+	}
+	return stream.SendAndClose(out)
+}
+
+func bulkResult(index int32, res *objectvalue.Response) *pb.BulkUpsertResult {
+	if !res.IsOk {
+		return &pb.BulkUpsertResult{Index: index, Code: res.Status, Error: res.Message}
+	}
+	return &pb.BulkUpsertResult{Index: index, Id: res.ID, Version: res.Version, Created: res.Version == 1}
+}
+
+// validateUser returns why a bulk loaded user can't be written, if it can't.
+func validateUser(user *pb.User) string {
+	switch {
+	case strings.TrimSpace(user.Name) == "":
+		return "El nombre es requerido"
+	case strings.TrimSpace(user.Document) == "":
+		return "El documento es requerido"
+	}
+	return ""
+}
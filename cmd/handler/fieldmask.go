@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"template-grpc/internal/domain/entity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	irepository "template-grpc/internal/domain/repository/interface"
+
+	pb "template-grpc/internal/infra/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// updatablePaths are the user fields UpdateUser may change.
+var updatablePaths = []string{"name", "document", "phone"}
+
+// UpdateUser reads the user, applies the fields in the mask and saves it at
+// the version read, in one unit of work retried after deadlocks. An edit
+// made in between fails with codes.Aborted instead of being overwritten.
+// Like the other writes it honors an idempotency key.
+func (s *server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.Response, error) {
+	paths, err := updatePaths(req.UpdateMask)
+	if err != nil {
+		return nil, err
+	}
+	patch := req.GetUser()
+	version := patch.GetVersion()
+	if version == 0 {
+		if version, err = ifMatch(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	res := s.idempotent(ctx, "UpdateUser", req, func() *objectvalue.Response {
+		var res *objectvalue.Response
+		err := s.unitOfWork.Do(ctx, func(ctx context.Context, repos irepository.IRepositories) error {
+			var user entity.User
+			user, res = repos.Users().Get(ctx, patch.GetId())
+			if !res.IsOk {
+				return res.Err()
+			}
+			if version != 0 {
+				user.Version = version
+			}
+			for _, path := range paths {
+				switch path {
+				case "name":
+					user.Name = patch.GetName()
+				case "document":
+					user.Document = patch.GetDocument()
+				case "phone":
+					user.Phone = patch.GetPhone()
+				}
+			}
+			res = repos.Users().Update(ctx, user)
+			return res.Err()
+		})
+		if err != nil && (res == nil || res.IsOk) {
+			// The transaction failed to begin or to commit.
+			res = objectvalue.Fail(codes.Internal, err.Error())
+		}
+		return res
+	})
+	setETag(ctx, res)
+	return toResponse(res)
+}
+
+// updatePaths checks an update mask, expanding an empty one or "*" to
+// every updatable field.
+func updatePaths(mask *fieldmaskpb.FieldMask) ([]string, error) {
+	paths := mask.GetPaths()
+	if len(paths) == 0 || len(paths) == 1 && paths[0] == "*" {
+		return updatablePaths, nil
+	}
+	if err := checkPaths("update_mask", paths); err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if !contains(updatablePaths, path) {
+			return nil, status.Errorf(codes.InvalidArgument, "update_mask: el campo %q no se puede modificar", path)
+		}
+	}
+	return paths, nil
+}
+
+// readMask checks a read mask before the users it trims are loaded.
+func readMask(mask *fieldmaskpb.FieldMask) error {
+	return checkPaths("read_mask", mask.GetPaths())
+}
+
+// checkPaths rejects paths that are not top level fields of User.
+func checkPaths(name string, paths []string) error {
+	fields := (&pb.User{}).ProtoReflect().Descriptor().Fields()
+	for _, path := range paths {
+		if strings.Contains(path, ".") || fields.ByName(protoreflect.Name(path)) == nil {
+			return status.Errorf(codes.InvalidArgument, "%s: campo desconocido %q", name, path)
+		}
+	}
+	return nil
+}
+
+// trim clears the fields of user left out of a checked read mask.
+func trim(user *pb.User, mask *fieldmaskpb.FieldMask) *pb.User {
+	paths := mask.GetPaths()
+	if len(paths) == 0 {
+		return user
+	}
+	message := user.ProtoReflect()
+	var cleared []protoreflect.FieldDescriptor
+	message.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if !contains(paths, string(field.Name())) {
+			cleared = append(cleared, field)
+		}
+		return true
+	})
+	for _, field := range cleared {
+		message.Clear(field)
+	}
+	return user
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+
+	pb "template-grpc/internal/infra/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestUpdatePaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		mask    *fieldmaskpb.FieldMask
+		want    []string
+		wantErr bool
+	}{
+		{"no mask", nil, updatablePaths, false},
+		{"empty mask", &fieldmaskpb.FieldMask{}, updatablePaths, false},
+		{"wildcard", &fieldmaskpb.FieldMask{Paths: []string{"*"}}, updatablePaths, false},
+		{"some fields", &fieldmaskpb.FieldMask{Paths: []string{"phone", "name"}}, []string{"phone", "name"}, false},
+		{"read only field", &fieldmaskpb.FieldMask{Paths: []string{"version"}}, nil, true},
+		{"unknown field", &fieldmaskpb.FieldMask{Paths: []string{"email"}}, nil, true},
+		{"nested path", &fieldmaskpb.FieldMask{Paths: []string{"name.first"}}, nil, true},
+		{"wildcard among fields", &fieldmaskpb.FieldMask{Paths: []string{"*", "name"}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := updatePaths(tt.mask)
+			if tt.wantErr {
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("got %v, %v, want InvalidArgument", got, err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	full := func() *pb.User {
+		return &pb.User{Id: 7, Name: "Ana", Document: "1001", Phone: "555", Version: 2, UpdatedBy: "alice"}
+	}
+	tests := []struct {
+		name  string
+		paths []string
+		want  *pb.User
+	}{
+		{"no mask", nil, full()},
+		{"some fields", []string{"id", "name"}, &pb.User{Id: 7, Name: "Ana"}},
+		{"unset field", []string{"created_by"}, &pb.User{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mask *fieldmaskpb.FieldMask
+			if tt.paths != nil {
+				mask = &fieldmaskpb.FieldMask{Paths: tt.paths}
+			}
+			if got := trim(full(), mask); !proto.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMask(t *testing.T) {
+	for paths, ok := range map[string]bool{
+		"name":      true,
+		"document":  true,
+		"email":     false,
+		"name.full": false,
+	} {
+		err := readMask(&fieldmaskpb.FieldMask{Paths: []string{paths}})
+		if (err == nil) != ok {
+			t.Errorf("readMask(%q) = %v", paths, err)
+		}
+	}
+}
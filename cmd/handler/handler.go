@@ -2,42 +2,235 @@ package handler
 
 import (
 	"context"
-	"fmt"
-	repository "template-grpc/internal/domain/repository/implement/user"
+	"strconv"
+	"strings"
+	"sync"
+	"template-grpc/internal/domain/entity"
+	objectvalue "template-grpc/internal/domain/object-value"
 	irepository "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+	"time"
 
 	pb "template-grpc/internal/infra/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// readPrimaryHeader lets a caller read its own writes by skipping replicas.
+	readPrimaryHeader = "x-read-primary"
+	// ifMatchHeader carries the version the caller read, as an alternative
+	// to User.version; etagHeader sends the current one back.
+	ifMatchHeader = "if-match"
+	etagHeader    = "etag"
 )
 
-func NewServerUser(usercrud irepository.IUserCrud) *server {
+// ServerOptions tune the user server: Masking says which personal fields
+// responses hide.
+type ServerOptions struct {
+	Masking MaskingPolicy
+	Bulk    BulkOptions
+	Watch   WatchOptions
+	// Idempotency keys of Insert, Update and Delete.
+	Idempotency IdempotencyOptions
+}
+
+// NewServerUser serves users through usercrud, or unitOfWork where a call
+// reads and writes, and their changes through feed, remembering
+// idempotency keys in idempotency.
+func NewServerUser(usercrud irepository.IUserCrud, unitOfWork irepository.IUnitOfWork, auditLog irepository.IAuditLog, feed irepository.IUserFeed, idempotency irepository.IIdempotencyStore, opts ServerOptions) *server {
 	return &server{
-		userCrud: repository.NewRepository(),
+		userCrud:        usercrud,
+		unitOfWork:      unitOfWork,
+		auditLog:        auditLog,
+		feed:            feed,
+		idempotency:     idempotency,
+		masking:         opts.Masking,
+		bulk:            opts.Bulk,
+		watch:           opts.Watch.withDefaults(),
+		idempotencyOpts: opts.Idempotency.withDefaults(),
+		closing:         make(chan struct{}),
 	}
 }
 
 type server struct {
-	userCrud irepository.IUserCrud
+	userCrud        irepository.IUserCrud
+	unitOfWork      irepository.IUnitOfWork
+	auditLog        irepository.IAuditLog
+	feed            irepository.IUserFeed
+	idempotency     irepository.IIdempotencyStore
+	masking         MaskingPolicy
+	bulk            BulkOptions
+	watch           WatchOptions
+	idempotencyOpts IdempotencyOptions
+	closing         chan struct{}
+	closeOnce       sync.Once
 	pb.UnimplementedUserCrudServer
 }
 
-func (s *server) Insert(context context.Context, user *pb.User) (*pb.Response, error) {
+func (s *server) Insert(ctx context.Context, user *pb.User) (*pb.Response, error) {
+	res := s.idempotent(ctx, "Insert", user, func() *objectvalue.Response {
+		return s.userCrud.Insert(ctx, toEntity(user))
+	})
+	setETag(ctx, res)
+	return toResponse(res)
+}
 
-	fmt.Println(user)
-	return &pb.Response{
-		Id:      1,
-		IsOk:    true,
-		Message: "dasdasdsa",
-	}, nil
+func (s *server) Update(ctx context.Context, user *pb.User) (*pb.Response, error) {
+	u := toEntity(user)
+	if u.Version == 0 {
+		version, err := ifMatch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		u.Version = version
+	}
+	res := s.idempotent(ctx, "Update", user, func() *objectvalue.Response {
+		return s.userCrud.Update(ctx, u)
+	})
+	setETag(ctx, res)
+	return toResponse(res)
+}
+
+func (s *server) List(ctx context.Context, req *pb.ListRequest) (*pb.Users, error) {
+	if err := readMask(req.ReadMask); err != nil {
+		return nil, err
+	}
+	users, res := s.userCrud.List(readContext(ctx), irepository.ListQuery{
+		Filter:  req.Filter,
+		OrderBy: req.OrderBy,
+		Offset:  int(req.Offset),
+		Limit:   int(req.Limit),
+	})
+	if !res.IsOk {
+		return nil, toError(res)
+	}
+	out := &pb.Users{Users: make([]*pb.User, 0, len(users))}
+	for _, user := range users {
+		out.Users = append(out.Users, trim(s.masking.user(ctx, toProto(user)), req.ReadMask))
+	}
+	return out, nil
+}
+
+func (s *server) SearchUsers(ctx context.Context, req *pb.SearchUsersRequest) (*pb.Users, error) {
+	users, res := s.userCrud.Search(readContext(ctx), irepository.UserQuery{
+		Name:          req.Name,
+		NameMatch:     irepository.Match(req.NameMatch),
+		Document:      req.Document,
+		DocumentMatch: irepository.Match(req.DocumentMatch),
+		Phone:         req.Phone,
+		PhoneMatch:    irepository.Match(req.PhoneMatch),
+		Offset:        int(req.Offset),
+		Limit:         int(req.Limit),
+	})
+	if !res.IsOk {
+		return nil, toError(res)
+	}
+	out := &pb.Users{Users: make([]*pb.User, 0, len(users))}
+	for _, user := range users {
+		out.Users = append(out.Users, s.masking.user(ctx, toProto(user)))
+	}
+	return out, nil
+}
+
+func (s *server) Get(ctx context.Context, req *pb.GetRequest) (*pb.User, error) {
+	if err := readMask(req.ReadMask); err != nil {
+		return nil, err
+	}
+	user, res := s.userCrud.Get(readContext(ctx), req.Id)
+	if !res.IsOk {
+		return nil, toError(res)
+	}
+	setETag(ctx, res)
+	return trim(s.masking.user(ctx, toProto(user)), req.ReadMask), nil
 }
 
-func (s *server) Update(context.Context, *pb.User) (*pb.Response, error) {
-	return nil, nil
+func (s *server) Delete(ctx context.Context, user *pb.User) (*pb.Response, error) {
+	return toResponse(s.idempotent(ctx, "Delete", user, func() *objectvalue.Response {
+		return s.userCrud.Delete(ctx, user.Id)
+	}))
 }
 
-func (s *server) List(context.Context, *pb.ListRequest) (*pb.Users, error) {
-	return nil, nil
+func readContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(readPrimaryHeader) {
+		if value == "true" || value == "1" {
+			return database.WithPrimary(ctx)
+		}
+	}
+	return ctx
+}
+
+// ifMatch reads the version from if-match metadata, accepting "3", 3 and
+// W/"3". It returns 0 when the header is absent.
+func ifMatch(ctx context.Context) (uint64, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(ifMatchHeader)
+	if len(values) == 0 {
+		return 0, nil
+	}
+	tag := strings.Trim(strings.TrimPrefix(values[0], "W/"), `"`)
+	version, err := strconv.ParseUint(tag, 10, 64)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "if-match inválido: %q", values[0])
+	}
+	return version, nil
+}
+
+func setETag(ctx context.Context, res *objectvalue.Response) {
+	if !res.IsOk || res.Version == 0 {
+		return
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(etagHeader, `"`+strconv.FormatUint(res.Version, 10)+`"`))
+}
+
+func toEntity(user *pb.User) entity.User {
+	return entity.User{
+		ID:       user.Id,
+		Name:     user.Name,
+		Document: user.Document,
+		Phone:    user.Phone,
+		Version:  user.Version,
+	}
+}
+
+func toProto(user entity.User) *pb.User {
+	return &pb.User{
+		Id:        user.ID,
+		Name:      user.Name,
+		Document:  user.Document,
+		Phone:     user.Phone,
+		Version:   user.Version,
+		CreatedAt: timestamp(user.CreatedAt),
+		UpdatedAt: timestamp(user.UpdatedAt),
+		CreatedBy: user.CreatedBy,
+		UpdatedBy: user.UpdatedBy,
+	}
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func toResponse(res *objectvalue.Response) (*pb.Response, error) {
+	if !res.IsOk {
+		return nil, toError(res)
+	}
+	return &pb.Response{
+		Id:      res.ID,
+		IsOk:    res.IsOk,
+		Message: res.Message,
+		Version: res.Version,
+	}, nil
 }
 
-func (s *server) Delete(context.Context, *pb.User) (*pb.Response, error) {
-	return nil, nil
+func toError(res *objectvalue.Response) error {
+	return status.Error(codes.Code(res.Status), res.Message)
 }
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    uint64
+		wantErr bool
+	}{
+		{"absent", nil, 0, false},
+		{"bare", []string{"3"}, 3, false},
+		{"quoted", []string{`"3"`}, 3, false},
+		{"weak", []string{`W/"12"`}, 12, false},
+		{"first of several", []string{"4", "5"}, 4, false},
+		{"not a version", []string{`"abc"`}, 0, true},
+		{"negative", []string{"-1"}, 0, true},
+		{"wildcard", []string{"*"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.values != nil {
+				md := metadata.MD{}
+				md.Append(ifMatchHeader, tt.values...)
+				ctx = metadata.NewIncomingContext(ctx, md)
+			}
+			got, err := ifMatch(ctx)
+			if tt.wantErr {
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("got %d, %v, want InvalidArgument", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("got %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"template-grpc/internal/domain/identity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// idempotencyKeyHeader lets a client retry a write without repeating
+	// it; idempotencyReplayedHeader tells it the answer was replayed.
+	idempotencyKeyHeader      = "idempotency-key"
+	idempotencyReplayedHeader = "idempotency-replayed"
+	maxIdempotencyKeyLength   = 255
+)
+
+// IdempotencyOptions tune idempotency keys; zero values take the defaults
+// noted.
+type IdempotencyOptions struct {
+	// TTL keeps a finished request's response for its retries, 24h.
+	TTL time.Duration
+	// Lease is how long a request holds its key before a retry may run
+	// again, in case the server died before it finished, 1m.
+	Lease time.Duration
+}
+
+func (o IdempotencyOptions) withDefaults() IdempotencyOptions {
+	if o.TTL <= 0 {
+		o.TTL = 24 * time.Hour
+	}
+	if o.Lease <= 0 {
+		o.Lease = time.Minute
+	}
+	return o
+}
+
+// idempotent runs write once per idempotency key of the tenant. A retry
+// with the same key and request gets the first response back, failures
+// included, unless the write was cut off by the client's deadline or
+// cancellation and rolled back; then the key is freed and the retry
+// writes. Calls without the header just write.
+func (s *server) idempotent(ctx context.Context, method string, req proto.Message, write func() *objectvalue.Response) *objectvalue.Response {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(idempotencyKeyHeader)
+	if len(keys) == 0 || keys[0] == "" {
+		return write()
+	}
+	key := keys[0]
+	if len(key) > maxIdempotencyKeyLength {
+		return objectvalue.Fail(codes.InvalidArgument, "idempotency-key: admite hasta 255 caracteres")
+	}
+	hash, err := requestHash(ctx, method, req)
+	if err != nil {
+		return objectvalue.Fail(codes.Internal, err.Error())
+	}
+
+	token, replay, res := s.idempotency.Begin(ctx, key, hash, s.idempotencyOpts.Lease)
+	if !res.IsOk {
+		return res
+	}
+	if replay != nil {
+		grpc.SetHeader(ctx, metadata.Pairs(idempotencyReplayedHeader, "true"))
+		return replay
+	}
+
+	res = write()
+	// The outcome is stored even when the client has gone, since that is
+	// when it retries.
+	detached, cancel := context.WithTimeout(identity.NewContext(context.Background(), identity.FromContext(ctx)), 10*time.Second)
+	defer cancel()
+	var stored *objectvalue.Response
+	switch codes.Code(res.Status) {
+	case codes.Canceled, codes.DeadlineExceeded:
+		stored = s.idempotency.Abandon(detached, key, token)
+	default:
+		stored = s.idempotency.Finish(detached, key, token, res, s.idempotencyOpts.TTL)
+	}
+	if !stored.IsOk {
+		log.Printf("idempotency key %q: %s", key, stored.Message)
+	}
+	return res
+}
+
+// requestHash identifies a request by method, caller, if-match version and
+// content, so a key reused for anything else is told apart.
+func requestHash(ctx context.Context, method string, req proto.Message) (string, error) {
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	version := strings.Join(md.Get(ifMatchHeader), ",")
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(method), []byte(identity.Actor(ctx)), []byte(version), body} {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"template-grpc/internal/domain/identity"
+	"testing"
+
+	pb "template-grpc/internal/infra/proto"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRequestHash(t *testing.T) {
+	call := func(actor string, md ...string) context.Context {
+		ctx := identity.NewContext(context.Background(), identity.Identity{Actor: actor, Tenant: "acme"})
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(md...))
+	}
+	base := call("alice", "if-match", "1")
+	user := &pb.User{Id: 7, Name: "Ana"}
+	want, err := requestHash(base, "Update", user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		req    proto.Message
+		same   bool
+	}{
+		{"same request", call("alice", "if-match", "1"), "Update", &pb.User{Id: 7, Name: "Ana"}, true},
+		{"other headers", call("alice", "if-match", "1", "idempotency-key", "k"), "Update", user, true},
+		{"other method", base, "Insert", user, false},
+		{"other actor", call("bob", "if-match", "1"), "Update", user, false},
+		{"other if-match", call("alice", "if-match", "2"), "Update", user, false},
+		{"no if-match", call("alice"), "Update", user, false},
+		{"other content", base, "Update", &pb.User{Id: 7, Name: "Ana B"}, false},
+		{"other message", base, "Update", &pb.UpdateUserRequest{User: user}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := requestHash(tt.ctx, tt.method, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == want) != tt.same {
+				t.Fatalf("hash %s, base %s, want same = %v", got, want, tt.same)
+			}
+		})
+	}
+}
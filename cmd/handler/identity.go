@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"template-grpc/internal/domain/identity"
+
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationHeader = "authorization"
+	// actorHeader names the caller when no secret is configured.
+	actorHeader = "x-actor"
+)
+
+// IdentityOptions tell the interceptor how to resolve the caller. With a
+// Secret every call must carry a bearer token signed with it (HS256): its
+// sub claim is the actor, TenantClaim the tenant, which it must hold, and
+// RoleClaim the roles, given as a list or a space separated string. Without
+// one the actor and tenant come from metadata, for trusted networks only.
+type IdentityOptions struct {
+	Secret        string
+	TenantClaim   string
+	TenantHeader  string
+	RoleClaim     string
+	RequireTenant bool
+}
+
+// NewIdentityInterceptor puts the caller's identity into the context seen by
+// the handlers, rejecting missing or invalid tokens and, if required, calls
+// without a tenant.
+func NewIdentityInterceptor(opts IdentityOptions) grpc.UnaryServerInterceptor {
+	opts = opts.withDefaults()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := withIdentity(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamIdentityInterceptor does for streaming calls what
+// NewIdentityInterceptor does for unary ones.
+func NewStreamIdentityInterceptor(opts IdentityOptions) grpc.StreamServerInterceptor {
+	opts = opts.withDefaults()
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := withIdentity(stream.Context(), opts)
+		if err != nil {
+			return err
+		}
+		return handler(srv, identityStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func (opts IdentityOptions) withDefaults() IdentityOptions {
+	if opts.TenantClaim == "" {
+		opts.TenantClaim = "tenant"
+	}
+	if opts.TenantHeader == "" {
+		opts.TenantHeader = "x-tenant-id"
+	}
+	if opts.RoleClaim == "" {
+		opts.RoleClaim = "roles"
+	}
+	return opts
+}
+
+// identityStream hands the handler the context carrying the identity.
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s identityStream) Context() context.Context {
+	return s.ctx
+}
+
+// withIdentity trusts metadata only when no secret is set. Otherwise every
+// call needs a verified token, which alone names the actor and the tenant.
+func withIdentity(ctx context.Context, opts IdentityOptions) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if opts.Secret == "" {
+		id := identity.Identity{
+			Actor:  first(md, actorHeader),
+			Tenant: first(md, opts.TenantHeader),
+		}
+		if opts.RequireTenant && id.Tenant == "" {
+			return nil, status.Error(codes.Unauthenticated, "tenant requerido")
+		}
+		return identity.NewContext(ctx, id), nil
+	}
+
+	auth := first(md, authorizationHeader)
+	if auth == "" {
+		return nil, status.Error(codes.Unauthenticated, "token requerido")
+	}
+	claims, err := parseToken(auth, opts.Secret)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "token inválido")
+	}
+	tenant, _ := claims[opts.TenantClaim].(string)
+	if tenant == "" {
+		return nil, status.Error(codes.Unauthenticated, "el token no indica el tenant")
+	}
+	if header := first(md, opts.TenantHeader); header != "" && header != tenant {
+		return nil, status.Error(codes.PermissionDenied, "el tenant no coincide con el token")
+	}
+	actor, _ := claims["sub"].(string)
+	return identity.NewContext(ctx, identity.Identity{
+		Actor:  actor,
+		Tenant: tenant,
+		Roles:  roles(claims[opts.RoleClaim]),
+	}), nil
+}
+
+func parseToken(header, secret string) (jwt.MapClaims, error) {
+	if !strings.HasPrefix(header, "Bearer ") || secret == "" {
+		return nil, fmt.Errorf("expected a bearer token")
+	}
+	raw := strings.TrimPrefix(header, "Bearer ")
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return []byte(secret), nil
+	})
+	return claims, err
+}
+
+func roles(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		var out []string
+		for _, role := range value {
+			if role, ok := role.(string); ok {
+				out = append(out, role)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
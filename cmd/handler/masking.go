@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"template-grpc/internal/domain/identity"
+
+	pb "template-grpc/internal/infra/proto"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const maskRune = '*'
+
+// MaskRule hides a field from callers without one of Roles, keeping only
+// its last Visible characters.
+type MaskRule struct {
+	Visible int
+	Roles   []string
+}
+
+// MaskingPolicy maps field names, such as document and phone, to their
+// rule; fields without a rule are shown in full. RevealRoles may call
+// RevealUser to read a user unmasked.
+type MaskingPolicy struct {
+	Fields      map[string]MaskRule
+	RevealRoles []string
+}
+
+// mask returns value as the caller may see it.
+func (p MaskingPolicy) mask(ctx context.Context, field, value string) string {
+	rule, ok := p.Fields[field]
+	if !ok || identity.FromContext(ctx).HasRole(rule.Roles...) {
+		return value
+	}
+	// Values no longer than Visible are hidden whole.
+	runes := []rune(value)
+	hidden := len(runes) - rule.Visible
+	if hidden <= 0 || rule.Visible < 0 {
+		hidden = len(runes)
+	}
+	return strings.Repeat(string(maskRune), hidden) + string(runes[hidden:])
+}
+
+func (p MaskingPolicy) user(ctx context.Context, user *pb.User) *pb.User {
+	user.Name = p.mask(ctx, "name", user.Name)
+	user.Document = p.mask(ctx, "document", user.Document)
+	user.Phone = p.mask(ctx, "phone", user.Phone)
+	return user
+}
+
+// change masks the audited values of a field, which copy the user's.
+func (p MaskingPolicy) change(ctx context.Context, change *pb.FieldChange) {
+	for _, value := range []*structpb.Value{change.Before, change.After} {
+		if s, ok := value.GetKind().(*structpb.Value_StringValue); ok {
+			s.StringValue = p.mask(ctx, change.Field, s.StringValue)
+		}
+	}
+}
+
+func (p MaskingPolicy) canReveal(ctx context.Context) bool {
+	return identity.FromContext(ctx).HasRole(p.RevealRoles...)
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"template-grpc/internal/domain/entity"
+	irepository "template-grpc/internal/domain/repository/interface"
+
+	pb "template-grpc/internal/infra/proto"
+
+	"google.golang.org/grpc/status"
+)
+
+// StreamUsers sends users as the repository's cursor yields them. Send
+// blocks while the client's flow control window is full, so a slow reader
+// holds the cursor back instead of piling users up in memory.
+func (s *server) StreamUsers(req *pb.StreamUsersRequest, stream pb.UserCrud_StreamUsersServer) error {
+	if err := readMask(req.ReadMask); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	var sendErr error
+	res := s.userCrud.Stream(readContext(ctx), irepository.ListQuery{
+		Filter:  req.Filter,
+		OrderBy: req.OrderBy,
+	}, func(user entity.User) error {
+		sendErr = stream.Send(trim(s.masking.user(ctx, toProto(user)), req.ReadMask))
+		return sendErr
+	})
+	switch {
+	case sendErr != nil:
+		return sendErr
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case !res.IsOk:
+		return toError(res)
+	}
+	return nil
+}
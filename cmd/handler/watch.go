@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/filter"
+	irepository "template-grpc/internal/domain/repository/interface"
+	"time"
+
+	pb "template-grpc/internal/infra/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WatchOptions tune WatchUsers; zero values take the defaults noted.
+type WatchOptions struct {
+	// PollInterval between reads of the feed once a watcher caught up, 1s.
+	PollInterval time.Duration
+	// BatchSize changes are read per poll, 100.
+	BatchSize int
+}
+
+func (o WatchOptions) withDefaults() WatchOptions {
+	if o.PollInterval <= 0 {
+		o.PollInterval = time.Second
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	return o
+}
+
+var changeTypes = map[string]pb.ChangeType{
+	entity.EventUserCreated: pb.ChangeType_CHANGE_TYPE_CREATED,
+	entity.EventUserUpdated: pb.ChangeType_CHANGE_TYPE_UPDATED,
+	entity.EventUserDeleted: pb.ChangeType_CHANGE_TYPE_DELETED,
+}
+
+// WatchUsers follows the feed from the watcher's own token. Each watcher
+// reads at the pace it takes changes, so a slow one falls behind in the
+// feed without holding back writers or other watchers. Once caught up it
+// sends a checkpoint for changes its filter skipped, keeping its token
+// fresh. A watcher that stops reading only blocks its own sends, until it
+// cancels, keepalive finds its connection dead or max_connection_age
+// closes it.
+func (s *server) WatchUsers(req *pb.WatchUsersRequest, stream pb.UserCrud_WatchUsersServer) error {
+	ctx := stream.Context()
+	expr, err := filter.Parse(req.Filter, irepository.UserChangeFields)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "filter: "+err.Error())
+	}
+	types := map[pb.ChangeType]bool{}
+	for _, t := range req.Types {
+		types[t] = true
+	}
+
+	token, err := s.resumeToken(ctx, req.ResumeToken)
+	if err != nil {
+		return err
+	}
+	opts := s.watch
+	sent := token
+	for {
+		changes, res := s.feed.Changes(readContext(ctx), token, opts.BatchSize)
+		if !res.IsOk {
+			return watchError(ctx, toError(res))
+		}
+		for _, change := range changes {
+			token = change.Token
+			changeType := changeTypes[change.Type]
+			if len(types) > 0 && !types[changeType] || !filter.Eval(expr, change.Value) {
+				continue
+			}
+			if err := stream.Send(&pb.UserChange{
+				Type:        changeType,
+				User:        s.masking.user(ctx, changeUser(change.User)),
+				Actor:       change.User.Actor,
+				OccurredAt:  timestamp(change.User.OccurredAt),
+				ResumeToken: strconv.FormatUint(token, 10),
+			}); err != nil {
+				return watchError(ctx, err)
+			}
+			sent = token
+		}
+		if len(changes) == opts.BatchSize {
+			continue
+		}
+
+		if sent != token {
+			if err := stream.Send(&pb.UserChange{ResumeToken: strconv.FormatUint(token, 10)}); err != nil {
+				return watchError(ctx, err)
+			}
+			sent = token
+		}
+		select {
+		case <-ctx.Done():
+			return watchError(ctx, ctx.Err())
+		case <-s.closing:
+			return status.Error(codes.Unavailable, "el servidor se está cerrando, reanude con el último resume_token")
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
+
+// StopWatches ends every watch, so a graceful stop doesn't wait for them.
+func (s *server) StopWatches() {
+	s.closeOnce.Do(func() { close(s.closing) })
+}
+
+func (s *server) resumeToken(ctx context.Context, token string) (uint64, error) {
+	if token == "" {
+		head, res := s.feed.Head(ctx)
+		if !res.IsOk {
+			return 0, toError(res)
+		}
+		return head, nil
+	}
+	after, err := strconv.ParseUint(token, 10, 64)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "resume_token inválido: %q", token)
+	}
+	return after, nil
+}
+
+// watchError reports a cancelled watch as such rather than as the error it
+// caused.
+func watchError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return err
+}
+
+func changeUser(user entity.UserEvent) *pb.User {
+	return &pb.User{
+		Id:        user.ID,
+		Name:      user.Name,
+		Document:  user.Document,
+		Phone:     user.Phone,
+		Version:   user.Version,
+		UpdatedAt: timestamp(user.OccurredAt),
+		UpdatedBy: user.Actor,
+	}
+}
@@ -1,23 +1,145 @@
 package main
 
 import (
+	"context"
+	"errors"
+	_ "expvar"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"template-grpc/cmd/config"
-
-	"google.golang.org/grpc"
+	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := config.Migrate(os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "reencrypt" {
+		if err := config.Reencrypt(os.Args[2:]); err != nil {
+			log.Fatalf("reencrypt: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "outbox" {
+		if err := config.Outbox(os.Args[2:]); err != nil {
+			log.Fatalf("outbox: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		if err := config.Seed(os.Args[2:]); err != nil {
+			log.Fatalf("seed: %v", err)
+		}
+		return
+	}
+
 	conf := config.GetConfig()
-	listener, err := net.Listen("tcp", ":"+conf.Server.Port)
+	listeners, err := config.Listen(conf.Server)
 	if err != nil {
-		panic(err)
+		log.Fatalf("failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
+	s, err := config.NewServer(conf.Server)
+	if err != nil {
+		panic(err)
+	}
 	s = config.Run(s, "")
-	if err := s.Serve(listener); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	stopOutbox, err := config.StartOutbox(conf)
+	if err != nil {
+		log.Fatalf("failed to start the outbox relay: %v", err)
+	}
+
+	errs := make(chan error, len(listeners)+2)
+	var httpServers []*http.Server
+
+	web := conf.Server.GrpcWeb
+	if web.Enabled && web.Multiplex {
+		srv := config.NewGrpcWebServer(s, conf.Server)
+		httpServers = append(httpServers, srv)
+		for _, listener := range listeners {
+			go serveHTTP(srv, listener, errs)
+		}
+	} else {
+		for _, listener := range listeners {
+			go func(listener net.Listener) {
+				errs <- s.Serve(listener)
+			}(listener)
+		}
+		if web.Enabled {
+			webListener, err := net.Listen("tcp", ":"+web.Port)
+			if err != nil {
+				panic(err)
+			}
+			srv := config.NewGrpcWebServer(s, conf.Server)
+			httpServers = append(httpServers, srv)
+			go serveHTTP(srv, webListener, errs)
+		}
+	}
+
+	if port := conf.Server.MetricsPort; port != "" {
+		metricsListener, err := net.Listen("tcp", ":"+port)
+		if err != nil {
+			panic(err)
+		}
+		// expvar registers /debug/vars on the default mux.
+		srv := &http.Server{Handler: http.DefaultServeMux}
+		httpServers = append(httpServers, srv)
+		go serveHTTP(srv, metricsListener, errs)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	exitCode := 0
+	select {
+	case err := <-errs:
+		log.Printf("failed to serve: %v", err)
+		exitCode = 1
+	case sig := <-stop:
+		log.Printf("shutting down on %s", sig)
+	}
+
+	// Watches never end on their own, so they go first or the servers
+	// would wait for them. Stopping the servers closes their listeners,
+	// which also removes unix socket files.
+	config.StopWatches()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	for _, srv := range httpServers {
+		srv.Shutdown(ctx)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		// A watcher that stopped reading keeps its send, and so its
+		// call, until the connection is closed.
+		s.Stop()
+	}
+	cancel()
+	stopOutbox()
+	// Nothing queries the database past this point.
+	if err := config.GetCluster().Close(); err != nil {
+		log.Printf("failed to close the database: %v", err)
+	}
+	for _, listener := range listeners {
+		listener.Close()
+	}
+	os.Exit(exitCode)
+}
+
+func serveHTTP(srv *http.Server, listener net.Listener, errs chan<- error) {
+	if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		errs <- err
 	}
 }
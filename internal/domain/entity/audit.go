@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ActionInsert = "insert"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	// ActionReveal records that someone read a user's personal data unmasked.
+	ActionReveal = "reveal"
+)
+
+// AuditEvent records one change to a row. Changes holds a JSON object
+// mapping each changed column to its before and after values; it is
+// encrypted at rest since it copies personal data.
+type AuditEvent struct {
+	ID        uint64    `gorm:"column:id;primary_key;auto_increment;"`
+	TenantID  string    `gorm:"column:tenant_id;not null;index;"`
+	Entity    string    `gorm:"column:entity;not null;"`
+	EntityID  uint64    `gorm:"column:entity_id;not null;"`
+	Action    string    `gorm:"column:action;not null;"`
+	Actor     string    `gorm:"column:actor;not null;"`
+	Changes   string    `gorm:"column:changes;not null;serializer:encrypted;"`
+	Reason    string    `gorm:"column:reason;not null;"`
+	CreatedAt time.Time `gorm:"column:created_at;not null;"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_log"
+}
+
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// NewAuditEvent diffs the before and after fields of a row; before is nil
+// for an insert and after is nil for a delete. Unchanged fields are left out.
+func NewAuditEvent(entity string, id uint64, action, actor string, before, after map[string]interface{}) (AuditEvent, error) {
+	changes := map[string]FieldChange{}
+	for field, value := range after {
+		if old, ok := before[field]; !ok || old != value {
+			changes[field] = FieldChange{Before: before[field], After: value}
+		}
+	}
+	for field, value := range before {
+		if _, ok := after[field]; !ok {
+			changes[field] = FieldChange{Before: value}
+		}
+	}
+
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return AuditEvent{}, err
+	}
+	return AuditEvent{
+		Entity:    entity,
+		EntityID:  id,
+		Action:    action,
+		Actor:     actor,
+		Changes:   string(encoded),
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// FieldChanges decodes Changes.
+func (e AuditEvent) FieldChanges() (map[string]FieldChange, error) {
+	changes := map[string]FieldChange{}
+	err := json.Unmarshal([]byte(e.Changes), &changes)
+	return changes, err
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// IdempotencyKey records a write made under a client's idempotency key.
+// Hash identifies the request the key was first used with and Token the
+// claim of the request running it; Response holds the JSON of its outcome
+// once it finished and is empty while it runs.
+type IdempotencyKey struct {
+	TenantID  string    `gorm:"column:tenant_id;primary_key;"`
+	Key       string    `gorm:"column:idempotency_key;primary_key;"`
+	Hash      string    `gorm:"column:request_hash;not null;"`
+	Token     string    `gorm:"column:lease_token;not null;"`
+	Response  string    `gorm:"column:response;not null;"`
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;"`
+	CreatedAt time.Time `gorm:"column:created_at;not null;"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
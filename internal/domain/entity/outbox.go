@@ -0,0 +1,88 @@
+package entity
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// TopicUsers is the topic of the user events.
+const TopicUsers = "users"
+
+const (
+	EventUserCreated = "user.created"
+	EventUserUpdated = "user.updated"
+	EventUserDeleted = "user.deleted"
+)
+
+const (
+	OutboxPending   = "pending"
+	OutboxPublished = "published"
+	// OutboxDead events ran out of attempts and wait to be requeued.
+	OutboxDead = "dead"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the
+// change it announces and published later by the relay. Tenant is not named
+// tenant_id so tenancy scoping leaves the table alone and the relay sees
+// every tenant's events.
+type OutboxEvent struct {
+	ID            uint64     `gorm:"column:id;primary_key;auto_increment;"`
+	Tenant        string     `gorm:"column:tenant;not null;"`
+	Topic         string     `gorm:"column:topic;not null;"`
+	Type          string     `gorm:"column:type;not null;"`
+	Key           string     `gorm:"column:event_key;not null;"`
+	Payload       string     `gorm:"column:payload;not null;serializer:encrypted;"`
+	Status        string     `gorm:"column:status;not null;"`
+	Attempts      int        `gorm:"column:attempts;not null;"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;not null;"`
+	LastError     string     `gorm:"column:last_error;not null;"`
+	CreatedAt     time.Time  `gorm:"column:created_at;not null;"`
+	PublishedAt   *time.Time `gorm:"column:published_at;"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
+
+// UserEvent is the payload of the user events: the user after the change,
+// or as it was for user.deleted.
+type UserEvent struct {
+	ID         uint64    `json:"id"`
+	Tenant     string    `json:"tenant"`
+	Name       string    `json:"name"`
+	Document   string    `json:"document"`
+	Phone      string    `json:"phone"`
+	Version    uint64    `json:"version"`
+	Actor      string    `json:"actor"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// NewUserEvent builds a pending event about user, keyed by its id so a
+// broker keeps one user's events in order.
+func NewUserEvent(eventType, tenant, actor string, user User) (OutboxEvent, error) {
+	now := time.Now().UTC()
+	payload, err := json.Marshal(UserEvent{
+		ID:         user.ID,
+		Tenant:     tenant,
+		Name:       user.Name,
+		Document:   user.Document,
+		Phone:      user.Phone,
+		Version:    user.Version,
+		Actor:      actor,
+		OccurredAt: now,
+	})
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	return OutboxEvent{
+		Tenant:        tenant,
+		Topic:         TopicUsers,
+		Type:          eventType,
+		Key:           strconv.FormatUint(user.ID, 10),
+		Payload:       string(payload),
+		Status:        OutboxPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
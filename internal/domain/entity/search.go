@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeName lower cases name and strips its accents, so "Núñez" and
+// "nunez" compare equal whatever the database collation.
+func NormalizeName(name string) string {
+	// Chains keep state, so each call needs its own.
+	foldAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(foldAccents, name)
+	if err != nil {
+		folded = name
+	}
+	return strings.ToLower(strings.Join(strings.Fields(folded), " "))
+}
@@ -1,8 +1,48 @@
 package entity
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User keeps Document and Phone encrypted at rest; DocumentHash and
+// PhoneHash are their blind indexes, the only way to look them up.
+// NameSearch is Name folded by NormalizeName.
 type User struct {
-	ID       uint64 `gorm:"column:id;primary_key;auto_increment;"`
-	Name     string `gorm:"column:name;not null;"`
-	Document string `gorm:"column:document;not null;"`
-	Phone    string `gorm:"column:phone;not null;"`
+	ID           uint64         `gorm:"column:id;primary_key;auto_increment;"`
+	TenantID     string         `gorm:"column:tenant_id;not null;uniqueIndex:idx_users_tenant_document_hash;"`
+	Name         string         `gorm:"column:name;not null;"`
+	Document     string         `gorm:"column:document;not null;serializer:encrypted;"`
+	DocumentHash string         `gorm:"column:document_hash;uniqueIndex:idx_users_tenant_document_hash;"`
+	Phone        string         `gorm:"column:phone;not null;serializer:encrypted;"`
+	PhoneHash    string         `gorm:"column:phone_hash;index;"`
+	NameSearch   string         `gorm:"column:name_search;not null;"`
+	Version      uint64         `gorm:"column:version;not null;default:1;"`
+	CreatedAt    time.Time      `gorm:"column:created_at;"`
+	UpdatedAt    time.Time      `gorm:"column:updated_at;"`
+	CreatedBy    string         `gorm:"column:created_by;not null;"`
+	UpdatedBy    string         `gorm:"column:updated_by;not null;"`
+	DeletedAt    gorm.DeletedAt `gorm:"column:deleted_at;index;"`
+}
+
+// AuditFields are the columns whose changes are kept in the audit log.
+func (u User) AuditFields() map[string]interface{} {
+	return map[string]interface{}{
+		"name":     u.Name,
+		"document": u.Document,
+		"phone":    u.Phone,
+	}
+}
+
+// UserSearchToken is the blind index of one prefix of a user's document or
+// phone, letting encrypted values be searched by prefix.
+type UserSearchToken struct {
+	UserID uint64 `gorm:"column:user_id;primary_key;"`
+	Field  string `gorm:"column:field;primary_key;"`
+	Token  string `gorm:"column:token;primary_key;"`
+}
+
+func (UserSearchToken) TableName() string {
+	return "user_search_tokens"
 }
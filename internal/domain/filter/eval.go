@@ -0,0 +1,60 @@
+package filter
+
+import "strings"
+
+// Eval reports whether the item whose fields value returns matches expr; a
+// nil expr matches everything. value must return the kind of each field,
+// already folded when the field folds.
+func Eval(expr Expr, value func(field string) interface{}) bool {
+	switch e := expr.(type) {
+	case nil:
+		return true
+	case And:
+		return Eval(e.Left, value) && Eval(e.Right, value)
+	case Or:
+		return Eval(e.Left, value) || Eval(e.Right, value)
+	case Not:
+		return !Eval(e.Expr, value)
+	case Compare:
+		return e.matches(value(e.Field))
+	}
+	return false
+}
+
+func (c Compare) matches(v interface{}) bool {
+	if c.Prefix {
+		prefixed := strings.HasPrefix(v.(string), c.Value.(string))
+		return prefixed != (c.Op == Ne)
+	}
+	if c.Op == Has {
+		return strings.Contains(v.(string), c.Value.(string))
+	}
+	switch order := CompareValues(v, c.Value); c.Op {
+	case Eq:
+		return order == 0
+	case Ne:
+		return order != 0
+	case Lt:
+		return order < 0
+	case Le:
+		return order <= 0
+	case Gt:
+		return order > 0
+	case Ge:
+		return order >= 0
+	}
+	return false
+}
+
+// Less orders two items by orders, whose fields value returns for the item
+// at i or j.
+func Less(orders []Order, value func(i int, field string) interface{}) func(i, j int) bool {
+	return func(i, j int) bool {
+		for _, order := range orders {
+			if c := CompareValues(value(i, order.Field), value(j, order.Field)); c != 0 {
+				return (c < 0) != order.Desc
+			}
+		}
+		return false
+	}
+}
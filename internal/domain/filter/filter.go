@@ -0,0 +1,141 @@
+// Package filter parses AIP-160 style filters such as
+// `name:"Ana*" AND phone != ""` and orderings such as "name desc,id" over a
+// whitelist of fields. Parsing checks every field, comparator and value, so
+// whoever translates the result into a query never sees anything else.
+package filter
+
+import (
+	"fmt"
+	"time"
+)
+
+type Kind int
+
+const (
+	String Kind = iota
+	Number
+	Time
+)
+
+type Op string
+
+const (
+	Eq Op = "="
+	Ne Op = "!="
+	Lt Op = "<"
+	Le Op = "<="
+	Gt Op = ">"
+	Ge Op = ">="
+	// Has matches strings containing the value.
+	Has Op = ":"
+)
+
+// Field describes a field that filters and orderings may use.
+type Field struct {
+	Kind Kind
+	// Ops are the comparators allowed, every one when empty.
+	Ops []Op
+	// Sortable fields may appear in an ordering.
+	Sortable bool
+	// Fold, when set, is applied to string values before comparing.
+	Fold func(string) string
+	// MinPrefix is the shortest prefix a wildcard value may have.
+	MinPrefix int
+}
+
+func (f Field) allows(op Op) bool {
+	if op == Has && f.Kind != String {
+		return false
+	}
+	if len(f.Ops) == 0 {
+		return true
+	}
+	for _, allowed := range f.Ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields is the whitelist of an entity, by name.
+type Fields map[string]Field
+
+// Expr is a parsed filter: And, Or, Not or Compare.
+type Expr interface {
+	expr()
+}
+
+type And struct{ Left, Right Expr }
+
+type Or struct{ Left, Right Expr }
+
+type Not struct{ Expr Expr }
+
+// Compare holds a value already converted to the field's kind: a string,
+// a uint64 or a time.Time. A string value ending in * compared with =, !=
+// or : is a prefix; otherwise : looks for the value anywhere.
+type Compare struct {
+	Field  string
+	Op     Op
+	Value  interface{}
+	Prefix bool
+	// Pos is where the comparison starts, counting runes from 1.
+	Pos int
+}
+
+func (And) expr()     {}
+func (Or) expr()      {}
+func (Not) expr()     {}
+func (Compare) expr() {}
+
+// Order sorts by one field.
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+// Error points at the offending position, counting runes from 1.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s en la posición %d", e.Msg, e.Pos)
+}
+
+func errorf(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// CompareValues orders two values of the same kind, returning -1, 0 or 1.
+func CompareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case string:
+		b := b.(string)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case uint64:
+		b := b.(uint64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case time.Time:
+		b := b.(time.Time)
+		switch {
+		case a.Before(b):
+			return -1
+		case a.After(b):
+			return 1
+		}
+	}
+	return 0
+}
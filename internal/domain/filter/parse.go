@@ -0,0 +1,402 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits keep a hostile filter from costing more than a reasonable one:
+// MaxLength bytes of filter or ordering, parentheses nested MaxDepth deep
+// and MaxConditions comparisons.
+const (
+	MaxLength     = 8 << 10
+	MaxDepth      = 16
+	MaxConditions = 100
+)
+
+// Parse parses a filter over fields; an empty filter returns nil, which
+// matches everything. Following AIP-160, OR binds tighter than AND, and
+// terms separated only by spaces are joined by AND.
+//
+//	expression  = sequence { "AND" sequence }
+//	sequence    = factor { factor }
+//	factor      = term { "OR" term }
+//	term        = [ "NOT" | "-" ] simple
+//	simple      = "(" expression ")" | field comparator value
+//
+// A bare value may contain colons, so timestamps need no quotes, as in
+// updated_at > 2024-01-01T00:00:00Z.
+func Parse(input string, fields Fields) (Expr, error) {
+	if err := checkLength(input); err != nil {
+		return nil, err
+	}
+	p, err := newParser(input, fields)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind == tokenEOF {
+		return nil, nil
+	}
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errorf(tok.pos, "no se esperaba %q", tok.text)
+	}
+	return expr, nil
+}
+
+// ParseOrder parses a comma separated list of sortable fields, each
+// optionally followed by asc or desc.
+func ParseOrder(input string, fields Fields) ([]Order, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+	if err := checkLength(input); err != nil {
+		return nil, err
+	}
+	var orders []Order
+	pos := 1
+	for _, part := range strings.Split(input, ",") {
+		words := splitWords(part, pos)
+		pos += utf8.RuneCountInString(part) + 1
+		if len(words) == 0 {
+			return nil, errorf(pos-1, "se esperaba un campo")
+		}
+		name := words[0]
+		field, ok := fields[name.text]
+		switch {
+		case !ok:
+			return nil, errorf(name.pos, "campo desconocido %q", name.text)
+		case !field.Sortable:
+			return nil, errorf(name.pos, "no se puede ordenar por %q", name.text)
+		}
+		order := Order{Field: name.text}
+		if len(words) > 1 {
+			direction := words[1]
+			switch strings.ToLower(direction.text) {
+			case "asc":
+			case "desc":
+				order.Desc = true
+			default:
+				return nil, errorf(direction.pos, "se esperaba asc o desc")
+			}
+		}
+		if len(words) > 2 {
+			return nil, errorf(words[2].pos, "se esperaba una coma")
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// checkLength rejects input longer than MaxLength.
+func checkLength(input string) error {
+	if len(input) <= MaxLength {
+		return nil
+	}
+	return errorf(utf8.RuneCountInString(input[:MaxLength])+1, "se admiten hasta %d bytes", MaxLength)
+}
+
+type word struct {
+	text string
+	pos  int
+}
+
+// splitWords splits s, found at rune position pos of the input, on white
+// space.
+func splitWords(s string, pos int) []word {
+	var words []word
+	for i := 0; i < len(s); {
+		if strings.ContainsRune(" \t\r\n", rune(s[i])) {
+			i++
+			pos++
+			continue
+		}
+		start := i
+		for i < len(s) && !strings.ContainsRune(" \t\r\n", rune(s[i])) {
+			i++
+		}
+		words = append(words, word{text: s[start:i], pos: pos})
+		pos += utf8.RuneCountInString(s[start:i])
+	}
+	return words
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenMinus
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	tokens     []token
+	next       int
+	fields     Fields
+	depth      int
+	conditions int
+}
+
+func newParser(input string, fields Fields) (*parser, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{tokens: tokens, fields: fields}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokenWord && tok.text == word
+}
+
+func (p *parser) expression() (Expr, error) {
+	left, err := p.sequence()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		p.take()
+		right, err := p.sequence()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) sequence() (Expr, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.startsTerm() {
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) startsTerm() bool {
+	switch tok := p.peek(); tok.kind {
+	case tokenLParen, tokenMinus:
+		return true
+	case tokenWord:
+		return tok.text != "AND" && tok.text != "OR"
+	}
+	return false
+}
+
+func (p *parser) factor() (Expr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		p.take()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) term() (Expr, error) {
+	if p.keyword("NOT") || p.peek().kind == tokenMinus {
+		p.take()
+		expr, err := p.simple()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+	}
+	return p.simple()
+}
+
+func (p *parser) simple() (Expr, error) {
+	tok := p.take()
+	switch {
+	case tok.kind == tokenLParen:
+		if p.depth++; p.depth > MaxDepth {
+			return nil, errorf(tok.pos, "los paréntesis se anidan más de %d niveles", MaxDepth)
+		}
+		expr, err := p.expression()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.take(); closing.kind != tokenRParen {
+			return nil, errorf(closing.pos, "se esperaba )")
+		}
+		return expr, nil
+	case tok.kind == tokenEOF:
+		return nil, errorf(tok.pos, "se esperaba una condición")
+	case tok.kind != tokenWord, tok.text == "AND", tok.text == "OR", tok.text == "NOT":
+		return nil, errorf(tok.pos, "se esperaba un campo y no %q", tok.text)
+	}
+	return p.restriction(tok)
+}
+
+func (p *parser) restriction(name token) (Expr, error) {
+	if p.conditions++; p.conditions > MaxConditions {
+		return nil, errorf(name.pos, "se admiten hasta %d condiciones", MaxConditions)
+	}
+	field, ok := p.fields[name.text]
+	if !ok {
+		return nil, errorf(name.pos, "campo desconocido %q", name.text)
+	}
+	op := p.take()
+	if op.kind != tokenOp {
+		return nil, errorf(op.pos, "se esperaba un comparador después de %q", name.text)
+	}
+	if !field.allows(Op(op.text)) {
+		return nil, errorf(op.pos, "el campo %q no admite %s", name.text, op.text)
+	}
+	value := p.take()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, errorf(value.pos, "se esperaba un valor")
+	}
+
+	compare := Compare{Field: name.text, Op: Op(op.text), Pos: name.pos}
+	switch field.Kind {
+	case Number:
+		n, err := strconv.ParseUint(value.text, 10, 64)
+		if err != nil {
+			return nil, errorf(value.pos, "se esperaba un número")
+		}
+		compare.Value = n
+	case Time:
+		t, err := time.Parse(time.RFC3339, value.text)
+		if err != nil {
+			return nil, errorf(value.pos, "se esperaba una fecha RFC 3339")
+		}
+		compare.Value = t.UTC()
+	default:
+		text := value.text
+		if compare.Op == Eq || compare.Op == Ne || compare.Op == Has {
+			compare.Prefix = strings.HasSuffix(text, "*")
+			text = strings.TrimSuffix(text, "*")
+		}
+		if field.Fold != nil {
+			text = field.Fold(text)
+		}
+		if compare.Prefix && utf8.RuneCountInString(text) < field.MinPrefix {
+			return nil, errorf(value.pos, "el prefijo debe tener al menos %d caracteres", field.MinPrefix)
+		}
+		compare.Value = text
+	}
+	return compare, nil
+}
+
+// tokenize scans input once, counting the runes it passes so each token
+// knows its position.
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	pos := 1
+	for i := 0; i < len(input); {
+		c := input[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: pos})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: pos})
+			i++
+		case c == '-':
+			tokens = append(tokens, token{kind: tokenMinus, text: "-", pos: pos})
+			i++
+		case strings.ContainsRune("=!<>:", rune(c)):
+			op := string(c)
+			if i+1 < len(input) && input[i+1] == '=' && c != '=' && c != ':' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, errorf(pos, "se esperaba !=")
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: pos})
+			i += len(op)
+		case c == '"':
+			text, n, err := unquote(input[i:])
+			if err != nil {
+				return nil, errorf(pos, "%s", err.Msg)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: pos})
+			i += n
+		default:
+			// After a comparator ':' belongs to the value, as in a time.
+			stops := " \t\r\n()=!<>:\""
+			if len(tokens) > 0 && tokens[len(tokens)-1].kind == tokenOp {
+				stops = " \t\r\n()=!<>\""
+			}
+			for i < len(input) && !strings.ContainsRune(stops, rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: input[start:i], pos: pos})
+		}
+		pos += utf8.RuneCountInString(input[start:i])
+	}
+	return append(tokens, token{kind: tokenEOF, pos: pos}), nil
+}
+
+// unquote reads the string literal at the start of s, returning its text
+// and how many bytes it took.
+func unquote(s string) (string, int, *Error) {
+	var text strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return text.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				break
+			}
+			i++
+			switch s[i] {
+			case '"', '\\':
+				text.WriteByte(s[i])
+			default:
+				text.WriteByte('\\')
+				text.WriteByte(s[i])
+			}
+		default:
+			text.WriteByte(s[i])
+		}
+	}
+	return "", 0, &Error{Msg: "falta cerrar las comillas"}
+}
@@ -0,0 +1,108 @@
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFields = Fields{
+	"name": {Kind: String, Sortable: true},
+	"id":   {Kind: Number, Sortable: true},
+	"at":   {Kind: Time, Sortable: true},
+}
+
+func TestParsePositions(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		pos   int
+	}{
+		{`name = "Núñez" AND nope = 1`, 20},
+		{`(name = "ñ"`, 12},
+		{`name = "ñ" !`, 12},
+		{`id = x`, 6},
+	} {
+		_, err := Parse(tc.input, testFields)
+		var perr *Error
+		if !errors.As(err, &perr) || perr.Pos != tc.pos {
+			t.Errorf("Parse(%q) = %v, want an error at %d", tc.input, err, tc.pos)
+		}
+	}
+
+	_, err := ParseOrder("name, ñame desc", testFields)
+	var perr *Error
+	if !errors.As(err, &perr) || perr.Pos != 7 {
+		t.Errorf("ParseOrder = %v, want an error at 7", err)
+	}
+	_, err = ParseOrder("name,,id", testFields)
+	if !errors.As(err, &perr) || perr.Pos != 6 {
+		t.Errorf("ParseOrder = %v, want an error at 6", err)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	at := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		input string
+		want  Expr
+	}{
+		{`at > 2024-01-01T10:30:00Z`, Compare{Field: "at", Op: Gt, Value: at, Pos: 1}},
+		{`at >= "2024-01-01T10:30:00Z"`, Compare{Field: "at", Op: Ge, Value: at, Pos: 1}},
+		{`at<2024-01-01T07:30:00-03:00`, Compare{Field: "at", Op: Lt, Value: at, Pos: 1}},
+		{`name:a:b`, Compare{Field: "name", Op: Has, Value: "a:b", Pos: 1}},
+		{`(at = 2024-01-01T10:30:00Z)`, Compare{Field: "at", Op: Eq, Value: at, Pos: 2}},
+	} {
+		got, err := Parse(tc.input, testFields)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.input, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Parse(%q) = %#v, want %#v", tc.input, got, tc.want)
+		}
+	}
+
+	if _, err := Parse(`name:`, testFields); err == nil {
+		t.Error("a comparator without a value parsed")
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	if _, err := Parse(strings.Repeat("(", MaxDepth)+"id = 1"+strings.Repeat(")", MaxDepth), testFields); err != nil {
+		t.Errorf("nested %d deep: %v", MaxDepth, err)
+	}
+	if _, err := Parse(strings.Repeat("(", MaxDepth+1)+"id = 1"+strings.Repeat(")", MaxDepth+1), testFields); err == nil {
+		t.Errorf("nested %d deep parsed", MaxDepth+1)
+	}
+
+	conditions := strings.TrimSuffix(strings.Repeat("id = 1 OR ", MaxConditions), " OR ")
+	if _, err := Parse(conditions, testFields); err != nil {
+		t.Errorf("%d conditions: %v", MaxConditions, err)
+	}
+	if _, err := Parse(conditions+" OR id = 2", testFields); err == nil {
+		t.Errorf("%d conditions parsed", MaxConditions+1)
+	}
+
+	for _, parse := range []func(string) error{
+		func(s string) error { _, err := Parse(s, testFields); return err },
+		func(s string) error { _, err := ParseOrder(s, testFields); return err },
+	} {
+		if err := parse(strings.Repeat(" ", MaxLength) + "x"); err == nil {
+			t.Error("input over MaxLength parsed")
+		}
+	}
+}
+
+// Tokens know their position without rescanning the input, so the cost
+// grows with its length and not with its square.
+func TestTokenizeLinear(t *testing.T) {
+	input := strings.Repeat(`ñ = "ñ" `, 100000)
+	start := time.Now()
+	if _, err := tokenize(input); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("tokenizing %d bytes took %s", len(input), elapsed)
+	}
+}
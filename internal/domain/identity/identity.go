@@ -0,0 +1,46 @@
+package identity
+
+import "context"
+
+// Anonymous is the actor recorded when a call carries no identity.
+const Anonymous = "anonymous"
+
+// Identity describes who is making a call. Tenant scopes every row the
+// call can see or change; the empty tenant is the default one. Roles only
+// come from a verified token.
+type Identity struct {
+	Actor  string
+	Tenant string
+	Roles  []string
+}
+
+// HasRole tells whether the caller holds any of roles.
+func (id Identity) HasRole(roles ...string) bool {
+	for _, held := range id.Roles {
+		for _, role := range roles {
+			if held == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type key struct{}
+
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+func FromContext(ctx context.Context) Identity {
+	id, _ := ctx.Value(key{}).(Identity)
+	return id
+}
+
+// Actor returns the caller's name, or Anonymous.
+func Actor(ctx context.Context) string {
+	if actor := FromContext(ctx).Actor; actor != "" {
+		return actor
+	}
+	return Anonymous
+}
@@ -1,8 +1,67 @@
 package objectvaule
 
+import "google.golang.org/grpc/codes"
+
+// Response carries the outcome of a repository call; Status holds a gRPC code.
 type Response struct {
+	ID      uint64
+	Version uint64
 	Title   string
 	Message string
 	IsOk    bool
 	Status  int32
+	// cause is the error behind a failure, kept for Err to wrap.
+	cause error
+}
+
+func Ok(id uint64, message string) *Response {
+	return &Response{
+		ID:      id,
+		Message: message,
+		IsOk:    true,
+		Status:  int32(codes.OK),
+	}
+}
+
+// Saved is Ok for a record that now has the given version.
+func Saved(id, version uint64, message string) *Response {
+	res := Ok(id, message)
+	res.Version = version
+	return res
+}
+
+func Fail(code codes.Code, message string) *Response {
+	return &Response{
+		Message: message,
+		Status:  int32(code),
+	}
+}
+
+// Because records the error that made the response fail.
+func (r *Response) Because(cause error) *Response {
+	r.cause = cause
+	return r
+}
+
+// Err returns nil for a successful response and the response as an error
+// otherwise, so a failed step can roll back a unit of work. The error wraps
+// the cause, so the unit of work can still tell a deadlock it may retry.
+func (r *Response) Err() error {
+	if r.IsOk {
+		return nil
+	}
+	return &Failure{Response: r}
+}
+
+// Failure is a failed Response used as an error.
+type Failure struct {
+	*Response
+}
+
+func (f *Failure) Error() string {
+	return f.Message
+}
+
+func (f *Failure) Unwrap() error {
+	return f.cause
 }
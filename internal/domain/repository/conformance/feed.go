@@ -0,0 +1,89 @@
+package conformance
+
+import (
+	"context"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// UserFeed checks that every write of the users repository reaches the feed,
+// in order and scoped to its tenant. newRepositories must return an empty
+// pair; a feed may hold changes back for a short while.
+func UserFeed(t *testing.T, newRepositories func(t *testing.T) (ireposity.IUserCrud, ireposity.IUserFeed)) {
+	acme := identity.NewContext(context.Background(), identity.Identity{Actor: "alice", Tenant: "acme"})
+	globex := identity.NewContext(context.Background(), identity.Identity{Actor: "bob", Tenant: "globex"})
+
+	t.Run("FollowsWrites", func(t *testing.T) {
+		repo, feed := newRepositories(t)
+		head, res := feed.Head(acme)
+		expectStatus(t, res, codes.OK)
+		if head != 0 {
+			t.Fatalf("empty feed head = %d", head)
+		}
+
+		res = repo.Insert(acme, user("1"))
+		expectStatus(t, res, codes.OK)
+		id := res.ID
+		expectStatus(t, repo.Insert(globex, user("2")), codes.OK)
+		expectStatus(t, repo.Update(acme, entity.User{ID: id, Name: "Ana", Document: "1", Version: 1}), codes.OK)
+		expectStatus(t, repo.Delete(acme, id), codes.OK)
+
+		changes := waitChanges(t, feed, acme, 0, 3)
+		for i, want := range []string{entity.EventUserCreated, entity.EventUserUpdated, entity.EventUserDeleted} {
+			change := changes[i]
+			if change.Type != want || change.User.ID != id || change.User.Tenant != "acme" || change.User.Actor != "alice" {
+				t.Fatalf("change %d = %+v, want %s", i, change, want)
+			}
+			if i > 0 && change.Token <= changes[i-1].Token {
+				t.Fatalf("tokens not increasing: %+v", changes)
+			}
+		}
+		if changes[1].User.Name != "Ana" || changes[1].User.Version != 2 || changes[1].User.Document != "1" {
+			t.Fatalf("updated user = %+v", changes[1].User)
+		}
+
+		head, _ = feed.Head(acme)
+		if head != changes[2].Token {
+			t.Fatalf("head = %d, want %d", head, changes[2].Token)
+		}
+		resumed, res := feed.Changes(acme, changes[0].Token, 1)
+		expectStatus(t, res, codes.OK)
+		if len(resumed) != 1 || resumed[0].Token != changes[1].Token {
+			t.Fatalf("resumed after %d = %+v", changes[0].Token, resumed)
+		}
+		rest, _ := feed.Changes(acme, head, 10)
+		if len(rest) != 0 {
+			t.Fatalf("changes after head = %+v", rest)
+		}
+
+		others := waitChanges(t, feed, globex, 0, 1)
+		if others[0].User.Document != "2" {
+			t.Fatalf("globex sees %+v", others)
+		}
+	})
+}
+
+// waitChanges reads the changes after token until there are want of them.
+func waitChanges(t *testing.T, feed ireposity.IUserFeed, ctx context.Context, after uint64, want int) []ireposity.UserChange {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		changes, res := feed.Changes(ctx, after, 100)
+		expectStatus(t, res, codes.OK)
+		if len(changes) >= want {
+			if len(changes) > want {
+				t.Fatalf("got %d changes, want %d: %+v", len(changes), want, changes)
+			}
+			return changes
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d changes, want %d: %+v", len(changes), want, changes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
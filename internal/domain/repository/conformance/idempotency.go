@@ -0,0 +1,115 @@
+package conformance
+
+import (
+	"context"
+	"template-grpc/internal/domain/identity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// IdempotencyStore checks that keys are claimed once per tenant, replayed
+// to the same request, failures included, and refused to others, and that
+// only the request holding a claim stores its answer; newStore must return
+// an empty store on every call.
+func IdempotencyStore(t *testing.T, newStore func(t *testing.T) ireposity.IIdempotencyStore) {
+	acme := identity.NewContext(context.Background(), identity.Identity{Actor: "alice", Tenant: "acme"})
+	globex := identity.NewContext(context.Background(), identity.Identity{Actor: "bob", Tenant: "globex"})
+
+	t.Run("ReplaysFinished", func(t *testing.T) {
+		store := newStore(t)
+		token, replay, res := store.Begin(acme, "k1", "hash", time.Minute)
+		expectStatus(t, res, codes.OK)
+		if replay != nil || token == "" {
+			t.Fatalf("new key replayed %+v with token %q", replay, token)
+		}
+		_, _, res = store.Begin(acme, "k1", "hash", time.Minute)
+		expectStatus(t, res, codes.Aborted)
+		_, _, res = store.Begin(globex, "k1", "other", time.Minute)
+		expectStatus(t, res, codes.OK)
+
+		expectStatus(t, store.Finish(acme, "k1", token, objectvalue.Saved(7, 1, "creado"), time.Hour), codes.OK)
+		_, replay, res = store.Begin(acme, "k1", "hash", time.Minute)
+		expectStatus(t, res, codes.OK)
+		if replay == nil || !replay.IsOk || replay.ID != 7 || replay.Version != 1 || replay.Message != "creado" {
+			t.Fatalf("replayed %+v", replay)
+		}
+		_, _, res = store.Begin(acme, "k1", "changed", time.Minute)
+		expectStatus(t, res, codes.FailedPrecondition)
+	})
+
+	t.Run("ReplaysFailures", func(t *testing.T) {
+		store := newStore(t)
+		token, _, res := store.Begin(acme, "k1", "hash", time.Minute)
+		expectStatus(t, res, codes.OK)
+		expectStatus(t, store.Finish(acme, "k1", token, objectvalue.Fail(codes.NotFound, "no existe"), time.Hour), codes.OK)
+
+		_, replay, res := store.Begin(acme, "k1", "hash", time.Minute)
+		expectStatus(t, res, codes.OK)
+		if replay == nil || replay.IsOk || codes.Code(replay.Status) != codes.NotFound || replay.Message != "no existe" {
+			t.Fatalf("replayed %+v, want the first failure", replay)
+		}
+	})
+
+	t.Run("AbandonFreesKey", func(t *testing.T) {
+		store := newStore(t)
+		token, _, res := store.Begin(acme, "k1", "hash", time.Minute)
+		expectStatus(t, res, codes.OK)
+		expectStatus(t, store.Abandon(acme, "k1", token), codes.OK)
+		token, replay, res := store.Begin(acme, "k1", "other", time.Minute)
+		expectStatus(t, res, codes.OK)
+		if replay != nil {
+			t.Fatalf("abandoned key replayed %+v", replay)
+		}
+
+		expectStatus(t, store.Finish(acme, "k1", token, objectvalue.Ok(1, ""), time.Hour), codes.OK)
+		expectStatus(t, store.Abandon(acme, "k1", token), codes.OK)
+		_, replay, _ = store.Begin(acme, "k1", "other", time.Minute)
+		if replay == nil {
+			t.Fatal("abandon dropped a finished key")
+		}
+	})
+
+	t.Run("FinishNeedsTheLease", func(t *testing.T) {
+		store := newStore(t)
+		late, _, res := store.Begin(acme, "k1", "hash", 50*time.Millisecond)
+		expectStatus(t, res, codes.OK)
+		time.Sleep(100 * time.Millisecond)
+		retry, _, res := store.Begin(acme, "k1", "hash", time.Minute)
+		expectStatus(t, res, codes.OK)
+
+		// The first request outlived its lease, so its answer is not kept
+		// and it can't free the retry's claim.
+		expectStatus(t, store.Finish(acme, "k1", late, objectvalue.Ok(1, "tarde"), time.Hour), codes.Aborted)
+		expectStatus(t, store.Abandon(acme, "k1", late), codes.OK)
+		_, _, res = store.Begin(acme, "k1", "hash", time.Minute)
+		expectStatus(t, res, codes.Aborted)
+
+		expectStatus(t, store.Finish(acme, "k1", retry, objectvalue.Ok(2, "reintento"), time.Hour), codes.OK)
+		_, replay, _ := store.Begin(acme, "k1", "hash", time.Minute)
+		if replay == nil || replay.ID != 2 {
+			t.Fatalf("replayed %+v, want the retry's response", replay)
+		}
+	})
+
+	t.Run("Expires", func(t *testing.T) {
+		store := newStore(t)
+		_, _, res := store.Begin(acme, "leased", "hash", 50*time.Millisecond)
+		expectStatus(t, res, codes.OK)
+		token, _, res := store.Begin(acme, "finished", "hash", time.Minute)
+		expectStatus(t, res, codes.OK)
+		expectStatus(t, store.Finish(acme, "finished", token, objectvalue.Ok(1, ""), 50*time.Millisecond), codes.OK)
+		time.Sleep(100 * time.Millisecond)
+
+		for _, key := range []string{"leased", "finished"} {
+			_, replay, res := store.Begin(acme, key, "other", time.Minute)
+			expectStatus(t, res, codes.OK)
+			if replay != nil {
+				t.Fatalf("expired key %s replayed %+v", key, replay)
+			}
+		}
+	})
+}
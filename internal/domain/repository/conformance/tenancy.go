@@ -0,0 +1,74 @@
+package conformance
+
+import (
+	"context"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Tenancy checks that no tenant can see or change another tenant's users or
+// audit events; newRepositories must return an empty pair.
+func Tenancy(t *testing.T, newRepositories func(t *testing.T) (ireposity.IUserCrud, ireposity.IAuditLog)) {
+	acme := identity.NewContext(context.Background(), identity.Identity{Actor: "alice", Tenant: "acme"})
+	globex := identity.NewContext(context.Background(), identity.Identity{Actor: "bob", Tenant: "globex"})
+
+	t.Run("IsolatesUsers", func(t *testing.T) {
+		repo, _ := newRepositories(t)
+		res := repo.Insert(acme, user("1"))
+		expectStatus(t, res, codes.OK)
+		id := res.ID
+
+		_, res = repo.Get(globex, id)
+		expectStatus(t, res, codes.NotFound)
+		expectStatus(t, repo.Update(globex, entity.User{ID: id, Name: "Stolen", Document: "1", Version: 1}), codes.NotFound)
+		expectStatus(t, repo.Delete(globex, id), codes.NotFound)
+		users, res := repo.List(globex, ireposity.ListQuery{})
+		expectStatus(t, res, codes.OK)
+		if len(users) != 0 {
+			t.Fatalf("globex lists acme users: %+v", users)
+		}
+		expectStatus(t, repo.Stream(globex, ireposity.ListQuery{}, func(user entity.User) error {
+			t.Fatalf("globex streams acme user %+v", user)
+			return nil
+		}), codes.OK)
+		users, res = repo.Search(globex, ireposity.UserQuery{Document: "1"})
+		expectStatus(t, res, codes.OK)
+		if len(users) != 0 {
+			t.Fatalf("globex finds acme users: %+v", users)
+		}
+		users, res = repo.GetMany(globex, []uint64{id})
+		expectStatus(t, res, codes.OK)
+		if len(users) != 0 {
+			t.Fatalf("globex gets acme users: %+v", users)
+		}
+
+		got, res := repo.Get(acme, id)
+		expectStatus(t, res, codes.OK)
+		if got.TenantID != "acme" || got.Name != "User 1" {
+			t.Fatalf("acme user changed: %+v", got)
+		}
+	})
+
+	t.Run("DocumentsPerTenant", func(t *testing.T) {
+		repo, _ := newRepositories(t)
+		expectStatus(t, repo.Insert(acme, user("1")), codes.OK)
+		expectStatus(t, repo.Insert(globex, user("1")), codes.OK)
+		expectStatus(t, repo.Insert(globex, user("1")), codes.AlreadyExists)
+	})
+
+	t.Run("IsolatesAuditEvents", func(t *testing.T) {
+		repo, log := newRepositories(t)
+		repo.Insert(acme, user("1"))
+		repo.Insert(globex, user("2"))
+
+		events, res := log.List(acme, ireposity.AuditFilter{})
+		expectStatus(t, res, codes.OK)
+		if len(events) != 1 || events[0].Actor != "alice" || events[0].TenantID != "acme" {
+			t.Fatalf("acme events = %+v", events)
+		}
+	})
+}
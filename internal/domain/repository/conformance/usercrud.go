@@ -0,0 +1,478 @@
+// Package conformance holds the behaviour every IUserCrud implementation
+// must share, so the GORM and in-memory repositories stay interchangeable.
+// Call UserCrud from each implementation's tests.
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// UserCrud runs the suite; newRepository must return an empty repository
+// on every call.
+func UserCrud(t *testing.T, newRepository func(t *testing.T) ireposity.IUserCrud) {
+	ctx := context.Background()
+
+	t.Run("InsertAllocatesIDs", func(t *testing.T) {
+		repo := newRepository(t)
+		first := mustInsert(t, repo, user("1"))
+		second := mustInsert(t, repo, user("2"))
+		if first == 0 || second <= first {
+			t.Fatalf("ids not increasing: %d then %d", first, second)
+		}
+
+		got, res := repo.Get(ctx, second)
+		expectStatus(t, res, codes.OK)
+		if got.ID != second || got.Document != "2" || got.Name != "User 2" || got.Version != 1 {
+			t.Fatalf("Get(%d) = %+v", second, got)
+		}
+	})
+
+	t.Run("InsertIgnoresGivenID", func(t *testing.T) {
+		repo := newRepository(t)
+		u := user("1")
+		u.ID = 999
+		if id := mustInsert(t, repo, u); id == 999 {
+			t.Fatal("caller chose the id")
+		}
+	})
+
+	t.Run("DuplicateDocument", func(t *testing.T) {
+		repo := newRepository(t)
+		mustInsert(t, repo, user("1"))
+		expectStatus(t, repo.Insert(ctx, user("1")), codes.AlreadyExists)
+
+		id := mustInsert(t, repo, user("2"))
+		taken := user("1")
+		taken.ID = id
+		taken.Version = 1
+		expectStatus(t, repo.Update(ctx, taken), codes.AlreadyExists)
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		repo := newRepository(t)
+		_, res := repo.Get(ctx, 42)
+		expectStatus(t, res, codes.NotFound)
+	})
+
+	t.Run("GetMany", func(t *testing.T) {
+		repo := newRepository(t)
+		first := mustInsert(t, repo, user("1"))
+		second := mustInsert(t, repo, user("2"))
+		deleted := mustInsert(t, repo, user("3"))
+		expectStatus(t, repo.Delete(ctx, deleted), codes.OK)
+
+		users, res := repo.GetMany(ctx, []uint64{second, 42, deleted, first, second})
+		expectStatus(t, res, codes.OK)
+		found := map[uint64]string{}
+		for _, user := range users {
+			found[user.ID] = user.Document
+		}
+		if len(users) != 2 || found[first] != "1" || found[second] != "2" {
+			t.Fatalf("GetMany = %+v", users)
+		}
+
+		users, res = repo.GetMany(ctx, nil)
+		expectStatus(t, res, codes.OK)
+		if len(users) != 0 {
+			t.Fatalf("GetMany(nil) = %+v", users)
+		}
+		_, res = repo.GetMany(ctx, make([]uint64, ireposity.MaxBatchGet+1))
+		expectStatus(t, res, codes.InvalidArgument)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepository(t)
+		id := mustInsert(t, repo, user("1"))
+		changed := entity.User{ID: id, Name: "Changed", Document: "1", Phone: "999", Version: 1}
+		res := repo.Update(ctx, changed)
+		expectStatus(t, res, codes.OK)
+		if res.Version != 2 {
+			t.Fatalf("version after update = %d, want 2", res.Version)
+		}
+
+		got, res := repo.Get(ctx, id)
+		if got.Name != "Changed" || got.Phone != "999" || got.Version != 2 || res.Version != 2 {
+			t.Fatalf("update not stored: %+v", got)
+		}
+	})
+
+	t.Run("UpdateStaleVersion", func(t *testing.T) {
+		repo := newRepository(t)
+		id := mustInsert(t, repo, user("1"))
+		first := entity.User{ID: id, Name: "First", Document: "1", Version: 1}
+		second := entity.User{ID: id, Name: "Second", Document: "1", Version: 1}
+		expectStatus(t, repo.Update(ctx, first), codes.OK)
+		expectStatus(t, repo.Update(ctx, second), codes.Aborted)
+
+		got, _ := repo.Get(ctx, id)
+		if got.Name != "First" {
+			t.Fatalf("stale update overwrote the user: %+v", got)
+		}
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		repo := newRepository(t)
+		expectStatus(t, repo.Update(ctx, entity.User{ID: 42, Document: "x", Version: 1}), codes.NotFound)
+		expectStatus(t, repo.Update(ctx, entity.User{Document: "x", Version: 1}), codes.InvalidArgument)
+
+		id := mustInsert(t, repo, user("1"))
+		expectStatus(t, repo.Update(ctx, entity.User{ID: id, Document: "1"}), codes.InvalidArgument)
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		repo := newRepository(t)
+		id := mustInsert(t, repo, user("1"))
+		keep := mustInsert(t, repo, user("2"))
+
+		// An id past 32 bits must not wrap around onto a small one.
+		expectStatus(t, repo.Delete(ctx, id+1<<32), codes.NotFound)
+		expectStatus(t, repo.Delete(ctx, id), codes.OK)
+		expectStatus(t, repo.Delete(ctx, id), codes.NotFound)
+
+		_, res := repo.Get(ctx, id)
+		expectStatus(t, res, codes.NotFound)
+		expectStatus(t, repo.Update(ctx, entity.User{ID: id, Document: "1", Version: 1}), codes.NotFound)
+
+		users, res := repo.List(ctx, ireposity.ListQuery{})
+		expectStatus(t, res, codes.OK)
+		if len(users) != 1 || users[0].ID != keep {
+			t.Fatalf("List after delete = %+v", users)
+		}
+
+		// The document of a deleted user stays reserved.
+		expectStatus(t, repo.Insert(ctx, user("1")), codes.AlreadyExists)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		repo := newRepository(t)
+		jose := mustInsert(t, repo, entity.User{Name: "José Núñez", Document: "1001", Phone: "3001112233"})
+		ana := mustInsert(t, repo, entity.User{Name: "Ana María", Document: "1002", Phone: "3104445566"})
+		joselito := mustInsert(t, repo, entity.User{Name: "joselito", Document: "2001", Phone: "3001119999"})
+		sale := mustInsert(t, repo, entity.User{Name: "50%_off", Document: "3001", Phone: "555"})
+
+		search := func(query ireposity.UserQuery, want ...uint64) {
+			t.Helper()
+			users, res := repo.Search(ctx, query)
+			expectStatus(t, res, codes.OK)
+			if len(users) != len(want) {
+				t.Fatalf("Search(%+v) = %+v, want ids %v", query, users, want)
+			}
+			for i, user := range users {
+				if user.ID != want[i] {
+					t.Fatalf("Search(%+v) = %+v, want ids %v", query, users, want)
+				}
+			}
+		}
+		search(ireposity.UserQuery{Name: "JOSE"}, jose, joselito)
+		search(ireposity.UserQuery{Name: "nunez"}, jose)
+		search(ireposity.UserQuery{Name: "maria", NameMatch: ireposity.MatchPrefix})
+		search(ireposity.UserQuery{Name: "Ana", NameMatch: ireposity.MatchPrefix}, ana)
+		search(ireposity.UserQuery{Name: "ana maría", NameMatch: ireposity.MatchExact}, ana)
+		search(ireposity.UserQuery{Name: "%"}, sale)
+		search(ireposity.UserQuery{Name: "_"}, sale)
+		search(ireposity.UserQuery{Document: "1001"}, jose)
+		search(ireposity.UserQuery{Document: "100"})
+		search(ireposity.UserQuery{Document: "100", DocumentMatch: ireposity.MatchPrefix}, jose, ana)
+		search(ireposity.UserQuery{Phone: "300111", PhoneMatch: ireposity.MatchPrefix}, jose, joselito)
+		search(ireposity.UserQuery{Phone: "300111", PhoneMatch: ireposity.MatchPrefix, Name: "lito"}, joselito)
+		search(ireposity.UserQuery{Phone: "555"}, sale)
+		search(ireposity.UserQuery{Name: "o", Offset: 1, Limit: 1}, joselito)
+
+		changed := entity.User{ID: jose, Name: "Pedro", Document: "9001", Phone: "3001112233", Version: 1}
+		expectStatus(t, repo.Update(ctx, changed), codes.OK)
+		search(ireposity.UserQuery{Document: "100", DocumentMatch: ireposity.MatchPrefix}, ana)
+		search(ireposity.UserQuery{Document: "900", DocumentMatch: ireposity.MatchPrefix}, jose)
+		search(ireposity.UserQuery{Name: "jose"}, joselito)
+		expectStatus(t, repo.Delete(ctx, ana), codes.OK)
+		search(ireposity.UserQuery{Document: "1002"})
+
+		for _, query := range []ireposity.UserQuery{
+			{},
+			{Name: "  "},
+			{Document: "10", DocumentMatch: ireposity.MatchPrefix},
+			{Phone: "300", PhoneMatch: ireposity.MatchContains},
+			{Name: "x", NameMatch: ireposity.Match(9)},
+		} {
+			_, res := repo.Search(ctx, query)
+			expectStatus(t, res, codes.InvalidArgument)
+		}
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		repo := newRepository(t)
+		jose := mustInsert(t, repo, entity.User{Name: "José Núñez", Document: "1001", Phone: "3001112233"})
+		ana := mustInsert(t, repo, entity.User{Name: "Ana María", Document: "1002", Phone: ""})
+		anabel := mustInsert(t, repo, entity.User{Name: "Anabel", Document: "2001", Phone: "3001119999"})
+		sale := mustInsert(t, repo, entity.User{Name: "50%_off", Document: "3001", Phone: "555"})
+
+		list := func(query ireposity.ListQuery, want ...uint64) {
+			t.Helper()
+			users, res := repo.List(ctx, query)
+			expectStatus(t, res, codes.OK)
+			if len(users) != len(want) {
+				t.Fatalf("List(%+v) = %+v, want ids %v", query, users, want)
+			}
+			for i, user := range users {
+				if user.ID != want[i] {
+					t.Fatalf("List(%+v) = %+v, want ids %v", query, users, want)
+				}
+			}
+		}
+		list(ireposity.ListQuery{Filter: `name:"Ana*" AND phone != ""`}, anabel)
+		list(ireposity.ListQuery{Filter: `name:"ANA*"`, OrderBy: "name desc"}, anabel, ana)
+		list(ireposity.ListQuery{Filter: `name:nunez`}, jose)
+		list(ireposity.ListQuery{Filter: `name = "jose nunez"`}, jose)
+		list(ireposity.ListQuery{Filter: `name:"%"`}, sale)
+		list(ireposity.ListQuery{Filter: `document = 1001 OR document = 3001`}, jose, sale)
+		list(ireposity.ListQuery{Filter: `document = "100*"`}, jose, ana)
+		list(ireposity.ListQuery{Filter: `document != "100*"`}, anabel, sale)
+		list(ireposity.ListQuery{Filter: `phone = "300111*" -name:ana`}, jose)
+		list(ireposity.ListQuery{Filter: fmt.Sprintf("NOT (id = %d OR id > %d) version = 1", jose, anabel)}, ana, anabel)
+		list(ireposity.ListQuery{Filter: `created_at >= "2000-01-01T00:00:00Z" updated_at < "2100-01-01T00:00:00Z"`}, jose, ana, anabel, sale)
+		list(ireposity.ListQuery{OrderBy: "name"}, sale, ana, anabel, jose)
+		list(ireposity.ListQuery{OrderBy: "version desc, id desc", Limit: 2}, sale, anabel)
+
+		for _, query := range []ireposity.ListQuery{
+			{Filter: "name"},
+			{Filter: `name = "Ana`},
+			{Filter: "salary > 10"},
+			{Filter: "(id = 1"},
+			{Filter: "id = uno"},
+			{Filter: "document:100"},
+			{Filter: "document < 100"},
+			{Filter: `phone = "30*"`},
+			{Filter: "id = 1 AND"},
+			{Filter: "created_at > ayer"},
+			{OrderBy: "phone"},
+			{OrderBy: "name sideways"},
+			{OrderBy: "name,,id"},
+		} {
+			_, res := repo.List(ctx, query)
+			expectStatus(t, res, codes.InvalidArgument)
+		}
+	})
+
+	t.Run("Upsert", func(t *testing.T) {
+		repo := newRepository(t)
+		existing := mustInsert(t, repo, user("1"))
+		deleted := mustInsert(t, repo, user("2"))
+		expectStatus(t, repo.Delete(ctx, deleted), codes.OK)
+
+		results := repo.Upsert(ctx, []entity.User{
+			{Name: "Ana", Document: "1", Phone: "555"},
+			{Name: "Luis", Document: "2"},
+			{Name: "Eva", Document: "3"},
+			{Name: "Eva María", Document: "3", Version: 9},
+		})
+		if len(results) != 4 {
+			t.Fatalf("Upsert returned %d results", len(results))
+		}
+		expectStatus(t, results[0], codes.OK)
+		expectStatus(t, results[1], codes.AlreadyExists)
+		expectStatus(t, results[2], codes.OK)
+		expectStatus(t, results[3], codes.OK)
+		if results[0].ID != existing || results[0].Version != 2 {
+			t.Fatalf("existing user upserted as %+v", results[0])
+		}
+		if results[2].Version != 1 || results[3].ID != results[2].ID || results[3].Version != 2 {
+			t.Fatalf("new user upserted as %+v then %+v", results[2], results[3])
+		}
+
+		got, _ := repo.Get(ctx, existing)
+		if got.Name != "Ana" || got.Phone != "555" {
+			t.Fatalf("upserted user = %+v", got)
+		}
+		got, _ = repo.Get(ctx, results[2].ID)
+		if got.Name != "Eva María" {
+			t.Fatalf("user upserted twice = %+v", got)
+		}
+		users, _ := repo.Search(ctx, ireposity.UserQuery{Name: "eva"})
+		if len(users) != 1 {
+			t.Fatalf("upserted users found by name: %+v", users)
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		repo := newRepository(t)
+		total := ireposity.MaxLimit + 5
+		for i := 0; i < total; i++ {
+			mustInsert(t, repo, user(fmt.Sprint(i)))
+		}
+		deleted := mustInsert(t, repo, user("deleted"))
+		expectStatus(t, repo.Delete(ctx, deleted), codes.OK)
+
+		var streamed []entity.User
+		res := repo.Stream(ctx, ireposity.ListQuery{OrderBy: "id desc", Limit: 1}, func(user entity.User) error {
+			streamed = append(streamed, user)
+			return nil
+		})
+		expectStatus(t, res, codes.OK)
+		if len(streamed) != total || streamed[0].ID <= streamed[1].ID || streamed[0].Document != fmt.Sprint(total-1) {
+			t.Fatalf("streamed %d users, first %+v", len(streamed), streamed[0])
+		}
+
+		var names []string
+		res = repo.Stream(ctx, ireposity.ListQuery{Filter: `name:"user 1*"`, OrderBy: "name"}, func(user entity.User) error {
+			names = append(names, user.Name)
+			return nil
+		})
+		expectStatus(t, res, codes.OK)
+		if len(names) != 16 || names[0] != "User 1" || names[1] != "User 10" {
+			t.Fatalf("streamed names %v", names)
+		}
+
+		calls := 0
+		res = repo.Stream(ctx, ireposity.ListQuery{}, func(entity.User) error {
+			calls++
+			return fmt.Errorf("client gone")
+		})
+		if res.IsOk || calls != 1 {
+			t.Fatalf("Stream after an error: %+v, %d calls", res, calls)
+		}
+		expectStatus(t, repo.Stream(ctx, ireposity.ListQuery{Filter: "id"}, nil), codes.InvalidArgument)
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		repo := newRepository(t)
+		total := ireposity.DefaultLimit + 5
+		ids := make([]uint64, 0, total)
+		for i := 0; i < total; i++ {
+			ids = append(ids, mustInsert(t, repo, user(fmt.Sprint(i))))
+		}
+
+		page, _ := repo.List(ctx, ireposity.ListQuery{})
+		if len(page) != ireposity.DefaultLimit {
+			t.Fatalf("default page has %d users", len(page))
+		}
+		page, _ = repo.List(ctx, ireposity.ListQuery{Offset: 3, Limit: 4})
+		if len(page) != 4 || page[0].ID != ids[3] || page[3].ID != ids[6] {
+			t.Fatalf("List(3, 4) = %+v", page)
+		}
+		page, _ = repo.List(ctx, ireposity.ListQuery{Offset: total - 2, Limit: 10})
+		if len(page) != 2 {
+			t.Fatalf("last page has %d users", len(page))
+		}
+		page, _ = repo.List(ctx, ireposity.ListQuery{Offset: total + 10, Limit: 10})
+		if len(page) != 0 {
+			t.Fatalf("page past the end has %d users", len(page))
+		}
+	})
+}
+
+func user(document string) entity.User {
+	return entity.User{Name: "User " + document, Document: document, Phone: "555-" + document}
+}
+
+func mustInsert(t *testing.T, repo ireposity.IUserCrud, u entity.User) uint64 {
+	t.Helper()
+	res := repo.Insert(context.Background(), u)
+	expectStatus(t, res, codes.OK)
+	return res.ID
+}
+
+func expectStatus(t *testing.T, res *objectvalue.Response, code codes.Code) {
+	t.Helper()
+	if res == nil {
+		t.Fatalf("nil response, want %s", code)
+	}
+	if codes.Code(res.Status) != code || res.IsOk != (code == codes.OK) {
+		t.Fatalf("got %s (%q), want %s", codes.Code(res.Status), res.Message, code)
+	}
+}
+
+// AuditLog checks that every write of the users repository lands in the
+// audit log it was created with; newRepositories must return an empty pair.
+func AuditLog(t *testing.T, newRepositories func(t *testing.T) (ireposity.IUserCrud, ireposity.IAuditLog)) {
+	alice := identity.NewContext(context.Background(), identity.Identity{Actor: "alice"})
+	bob := identity.NewContext(context.Background(), identity.Identity{Actor: "bob"})
+
+	t.Run("RecordsWrites", func(t *testing.T) {
+		repo, log := newRepositories(t)
+		res := repo.Insert(alice, user("1"))
+		expectStatus(t, res, codes.OK)
+		id := res.ID
+
+		got, _ := repo.Get(alice, id)
+		if got.CreatedBy != "alice" || got.UpdatedBy != "alice" || got.CreatedAt.IsZero() {
+			t.Fatalf("insert not stamped: %+v", got)
+		}
+
+		changed := user("1")
+		changed.ID, changed.Version, changed.Phone = id, 1, "999"
+		expectStatus(t, repo.Update(bob, changed), codes.OK)
+		got, _ = repo.Get(bob, id)
+		if got.CreatedBy != "alice" || got.UpdatedBy != "bob" || got.UpdatedAt.Before(got.CreatedAt) {
+			t.Fatalf("update not stamped: %+v", got)
+		}
+		expectStatus(t, repo.Delete(bob, id), codes.OK)
+
+		events, res := log.List(alice, ireposity.AuditFilter{EntityID: id})
+		expectStatus(t, res, codes.OK)
+		if len(events) != 3 {
+			t.Fatalf("got %d events, want 3", len(events))
+		}
+		wantActions := []string{entity.ActionInsert, entity.ActionUpdate, entity.ActionDelete}
+		wantActors := []string{"alice", "bob", "bob"}
+		for i, event := range events {
+			if event.Action != wantActions[i] || event.Actor != wantActors[i] || event.Entity != "users" {
+				t.Fatalf("event %d = %+v", i, event)
+			}
+		}
+
+		changes, err := events[1].FieldChanges()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 1 || changes["phone"].Before != "555-1" || changes["phone"].After != "999" {
+			t.Fatalf("update diff = %+v", changes)
+		}
+		changes, _ = events[2].FieldChanges()
+		if changes["document"].Before != "1" || changes["document"].After != nil {
+			t.Fatalf("delete diff = %+v", changes)
+		}
+	})
+
+	t.Run("FailedWritesLeaveNoEvent", func(t *testing.T) {
+		repo, log := newRepositories(t)
+		mustInsert(t, repo, user("1"))
+		expectStatus(t, repo.Insert(alice, user("1")), codes.AlreadyExists)
+		expectStatus(t, repo.Update(alice, entity.User{ID: 42, Version: 1}), codes.NotFound)
+
+		events, _ := log.List(alice, ireposity.AuditFilter{})
+		if len(events) != 1 {
+			t.Fatalf("got %d events, want 1", len(events))
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		repo, log := newRepositories(t)
+		first := repo.Insert(alice, user("1")).ID
+		repo.Insert(bob, user("2"))
+
+		events, _ := log.List(alice, ireposity.AuditFilter{Actor: "bob"})
+		if len(events) != 1 || events[0].Actor != "bob" {
+			t.Fatalf("actor filter = %+v", events)
+		}
+		events, _ = log.List(alice, ireposity.AuditFilter{Entity: "users", EntityID: first})
+		if len(events) != 1 || events[0].EntityID != first {
+			t.Fatalf("entity filter = %+v", events)
+		}
+		events, _ = log.List(alice, ireposity.AuditFilter{To: time.Now().Add(-time.Hour)})
+		if len(events) != 0 {
+			t.Fatalf("events before an hour ago = %+v", events)
+		}
+		events, _ = log.List(alice, ireposity.AuditFilter{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour)})
+		if len(events) != 2 {
+			t.Fatalf("events in the last hour = %+v", events)
+		}
+	})
+}
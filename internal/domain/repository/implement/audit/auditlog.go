@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"template-grpc/internal/domain/entity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+
+	"google.golang.org/grpc/codes"
+)
+
+type auditLog struct {
+	db database.Resolver
+}
+
+func NewRepository(db database.Resolver) ireposity.IAuditLog {
+	return &auditLog{db: db}
+}
+
+func (a *auditLog) Record(ctx context.Context, event entity.AuditEvent) error {
+	event.ID = 0
+	return a.db.Writer(ctx).Create(&event).Error
+}
+
+func (a *auditLog) List(ctx context.Context, filter ireposity.AuditFilter) ([]entity.AuditEvent, *objectvalue.Response) {
+	query := a.db.Reader(ctx).Model(&entity.AuditEvent{})
+	if filter.Entity != "" {
+		query = query.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To.UTC())
+	}
+
+	var events []entity.AuditEvent
+	err := query.
+		Order("created_at, id").
+		Offset(filter.Offset).
+		Limit(ireposity.PageSize(filter.Limit)).
+		Find(&events).Error
+	if err != nil {
+		return nil, objectvalue.Fail(codes.Internal, err.Error())
+	}
+	return events, objectvalue.Ok(0, "")
+}
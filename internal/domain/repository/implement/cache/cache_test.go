@@ -0,0 +1,214 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	"template-grpc/internal/domain/repository/conformance"
+	"template-grpc/internal/domain/repository/implement/cache"
+	"template-grpc/internal/domain/repository/implement/memory"
+	repository "template-grpc/internal/domain/repository/implement/user"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database/dbtest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	acme   = identity.NewContext(context.Background(), identity.Identity{Actor: "alice", Tenant: "acme"})
+	globex = identity.NewContext(context.Background(), identity.Identity{Actor: "bob", Tenant: "globex"})
+)
+
+// stores returns a fresh Store of each kind per call.
+func stores(t *testing.T) map[string]func() cache.Store {
+	server := miniredis.RunT(t)
+	return map[string]func() cache.Store{
+		"lru": func() cache.Store { return cache.NewLRU(100) },
+		"redis": func() cache.Store {
+			server.FlushAll()
+			return cache.NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}), "t:")
+		},
+	}
+}
+
+func TestConformance(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			conformance.UserCrud(t, func(t *testing.T) ireposity.IUserCrud {
+				return cache.NewRepository(memory.NewRepository(memory.NewAuditLog()), store(), time.Minute)
+			})
+			conformance.Tenancy(t, func(t *testing.T) (ireposity.IUserCrud, ireposity.IAuditLog) {
+				auditLog := memory.NewAuditLog()
+				return cache.NewRepository(memory.NewRepository(auditLog), store(), time.Minute), auditLog
+			})
+			conformance.UserCrud(t, func(t *testing.T) ireposity.IUserCrud {
+				return cache.NewRepository(repository.NewRepository(dbtest.Cluster(t)), store(), time.Minute)
+			})
+		})
+	}
+}
+
+func TestKeysScopedByTenantAndGeneration(t *testing.T) {
+	server := miniredis.RunT(t)
+	repo := cache.NewRepository(memory.NewRepository(memory.NewAuditLog()), cache.NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}), "t:"), time.Minute)
+	id := repo.Insert(acme, entity.User{Name: "Ana", Document: "1"}).ID
+	repo.Get(acme, id)
+	_, res := repo.Get(globex, id)
+	expectStatus(t, res, codes.NotFound)
+	for _, key := range []string{
+		"t:gen:users:acme",
+		fmt.Sprintf("t:users:acme:1:get:%d", id),
+	} {
+		if !server.Exists(key) {
+			t.Fatalf("missing %s in %v", key, server.Keys())
+		}
+	}
+	if server.Exists(fmt.Sprintf("t:users:globex:0:get:%d", id)) {
+		t.Fatal("cached another tenant's miss")
+	}
+
+	repo.Insert(globex, entity.User{Name: "Bob", Document: "1"})
+	if generation, _ := server.Get("t:gen:users:acme"); generation != "1" {
+		t.Fatalf("a globex write bumped acme to generation %s", generation)
+	}
+	expectStatus(t, repo.Update(acme, entity.User{ID: id, Name: "Ana B", Document: "1", Version: 1}), codes.OK)
+	if generation, _ := server.Get("t:gen:users:acme"); generation != "2" {
+		t.Fatalf("acme generation after a write = %s", generation)
+	}
+	user, _ := repo.Get(acme, id)
+	if user.Name != "Ana B" || !server.Exists(fmt.Sprintf("t:users:acme:2:get:%d", id)) {
+		t.Fatalf("read after write = %+v, keys %v", user, server.Keys())
+	}
+}
+
+func TestSealsPersonalData(t *testing.T) {
+	dbtest.Keyring(t)
+	server := miniredis.RunT(t)
+	repo := cache.NewRepository(memory.NewRepository(memory.NewAuditLog()), cache.NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}), "t:"), time.Minute)
+	id := repo.Insert(acme, entity.User{Name: "Ana", Document: "77001234", Phone: "555-0199"}).ID
+
+	for i := 0; i < 2; i++ {
+		if user, res := repo.Get(acme, id); !res.IsOk || user.Document != "77001234" || user.Phone != "555-0199" {
+			t.Fatalf("read %d = %+v, %s", i, user, res.Message)
+		}
+		users, res := repo.Search(acme, ireposity.UserQuery{Document: "77001234"})
+		if !res.IsOk || len(users) != 1 {
+			t.Fatalf("search %d = %+v, %s", i, users, res.Message)
+		}
+	}
+	for _, key := range server.Keys() {
+		value, _ := server.Get(key)
+		for _, secret := range []string{"77001234", "555-0199"} {
+			if strings.Contains(key, secret) || strings.Contains(value, secret) {
+				t.Fatalf("%s is readable in the store: %s = %s", secret, key, value)
+			}
+		}
+	}
+}
+
+// counting counts the reads that reach the repository.
+type counting struct {
+	ireposity.IUserCrud
+	gets atomic.Int64
+	// release, when set, holds each Get until it is closed.
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *counting) Get(ctx context.Context, id uint64) (entity.User, *objectvalue.Response) {
+	c.gets.Add(1)
+	if c.release != nil {
+		c.started <- struct{}{}
+		<-c.release
+		if err := ctx.Err(); err != nil {
+			return entity.User{}, objectvalue.Fail(codes.Canceled, err.Error())
+		}
+	}
+	return c.IUserCrud.Get(ctx, id)
+}
+
+func TestInvalidation(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			next := &counting{IUserCrud: memory.NewRepository(memory.NewAuditLog())}
+			repo := cache.NewRepository(next, store(), time.Minute)
+			id := repo.Insert(acme, entity.User{Name: "Ana", Document: "1"}).ID
+
+			repo.Get(acme, id)
+			repo.Get(acme, id)
+			if n := next.gets.Load(); n != 1 {
+				t.Fatalf("%d loads for two reads", n)
+			}
+			expectStatus(t, repo.Delete(acme, id), codes.OK)
+			_, res := repo.Get(acme, id)
+			expectStatus(t, res, codes.NotFound)
+			if n := next.gets.Load(); n != 2 {
+				t.Fatalf("%d loads after a write", n)
+			}
+		})
+	}
+}
+
+func TestCancelledCallerLeavesSharedLoad(t *testing.T) {
+	next := &counting{
+		IUserCrud: memory.NewRepository(memory.NewAuditLog()),
+		started:   make(chan struct{}, 1),
+		release:   make(chan struct{}),
+	}
+	repo := cache.NewRepository(next, cache.NewLRU(10), time.Minute)
+	id := repo.Insert(acme, entity.User{Name: "Ana", Document: "1"}).ID
+
+	ctx, cancel := context.WithCancel(acme)
+	first := make(chan *objectvalue.Response)
+	go func() {
+		_, res := repo.Get(ctx, id)
+		first <- res
+	}()
+	<-next.started
+	second := make(chan entity.User)
+	go func() {
+		user, _ := repo.Get(acme, id)
+		second <- user
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	expectStatus(t, <-first, codes.Canceled)
+	close(next.release)
+	if user := <-second; user.ID != id {
+		t.Fatalf("shared read = %+v", user)
+	}
+	if n := next.gets.Load(); n != 1 {
+		t.Fatalf("%d loads", n)
+	}
+}
+
+func TestZeroTTLKeepsValues(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			s := store()
+			if err := s.Set(acme, "k", []byte("v"), 0); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(10 * time.Millisecond)
+			if value, ok, err := s.Get(acme, "k"); err != nil || !ok || string(value) != "v" {
+				t.Fatalf("Get = %q, %v, %v", value, ok, err)
+			}
+		})
+	}
+}
+
+func expectStatus(t *testing.T, res *objectvalue.Response, code codes.Code) {
+	t.Helper()
+	if codes.Code(res.Status) != code {
+		t.Fatalf("got %s (%q), want %s", codes.Code(res.Status), res.Message, code)
+	}
+}
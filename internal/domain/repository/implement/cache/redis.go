@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisStore shares the cache between instances. Generations are plain
+// keys without expiry, so the server should evict with a volatile policy.
+type redisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedis creates a Store whose keys all start with prefix.
+func NewRedis(client redis.UniversalClient, prefix string) Store {
+	return &redisStore{client: client, prefix: prefix}
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+func (r *redisStore) Generation(ctx context.Context, scope string) (int64, error) {
+	generation, err := r.client.Get(ctx, r.prefix+"gen:"+scope).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return generation, err
+}
+
+func (r *redisStore) Bump(ctx context.Context, scope string) error {
+	return r.client.Incr(ctx, r.prefix+"gen:"+scope).Err()
+}
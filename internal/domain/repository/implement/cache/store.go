@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps cached values for a ttl, or until evicted when the ttl is 0.
+// Generations are counters that never expire: bumping a scope's generation
+// orphans every key built with the old one.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Generation(ctx context.Context, scope string) (int64, error)
+	Bump(ctx context.Context, scope string) error
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// lru is an in-process Store holding up to size values, evicting the least
+// recently used first; expired values are dropped when read. Like Redis, it
+// keeps values set with a ttl of 0 until they are evicted.
+type lru struct {
+	mu          sync.Mutex
+	size        int
+	order       *list.List
+	items       map[string]*list.Element
+	generations map[string]int64
+}
+
+// NewLRU creates an in-process Store; size defaults to 10000 values. Its
+// generations live in the process, so a write made by another instance
+// doesn't invalidate it: it only suits a single instance.
+func NewLRU(size int) Store {
+	if size <= 0 {
+		size = 10000
+	}
+	return &lru{
+		size:        size,
+		order:       list.New(),
+		items:       map[string]*list.Element{},
+		generations: map[string]int64{},
+	}
+}
+
+func (l *lru) Get(_ context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		l.remove(element)
+		return nil, false, nil
+	}
+	l.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (l *lru) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	if element, ok := l.items[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		l.order.MoveToFront(element)
+		return nil
+	}
+	l.items[key] = l.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for l.order.Len() > l.size {
+		l.remove(l.order.Back())
+	}
+	return nil
+}
+
+func (l *lru) Generation(_ context.Context, scope string) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.generations[scope], nil
+}
+
+func (l *lru) Bump(_ context.Context, scope string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.generations[scope]++
+	return nil
+}
+
+func (l *lru) remove(element *list.Element) {
+	l.order.Remove(element)
+	delete(l.items, element.Value.(*lruEntry).key)
+}
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"context"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"time"
+)
+
+type unitOfWork struct {
+	next  ireposity.IUnitOfWork
+	store Store
+	ttl   time.Duration
+}
+
+// NewUnitOfWork hands out the repositories of next wrapped with the cache,
+// so the writes of a unit of work invalidate it once they commit.
+func NewUnitOfWork(next ireposity.IUnitOfWork, store Store, ttl time.Duration) ireposity.IUnitOfWork {
+	return &unitOfWork{next: next, store: store, ttl: ttl}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos ireposity.IRepositories) error) error {
+	return u.next.Do(ctx, func(ctx context.Context, repos ireposity.IRepositories) error {
+		return fn(ctx, repositories{users: NewRepository(repos.Users(), u.store, u.ttl)})
+	})
+}
+
+type repositories struct {
+	users ireposity.IUserCrud
+}
+
+func (r repositories) Users() ireposity.IUserCrud {
+	return r.users
+}
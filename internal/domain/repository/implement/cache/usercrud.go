@@ -0,0 +1,218 @@
+// Package cache wraps IUserCrud with a read-through cache. Every read is
+// served from a Store keyed by tenant and generation; every successful write
+// bumps the tenant's generation, so no stale read outlives a write made
+// through the wrapper, or through another instance sharing a redis Store.
+// The memory Store only sees its own instance's writes, so it suits a
+// single instance only. Inside a transaction reads skip the cache and the
+// bump waits for the commit.
+//
+// Cached users carry the documents and phones encrypted at rest, so values
+// are sealed with the database keyring before they reach the store, and the
+// part of a key naming the query, which may hold a searched document, is
+// replaced by its blind index.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"strings"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics are published by expvar as user_cache: hits, misses, shared
+// (misses whose load was shared with concurrent callers) and errors of the
+// store.
+var Metrics = expvar.NewMap("user_cache")
+
+type userCache struct {
+	next  ireposity.IUserCrud
+	store Store
+	ttl   time.Duration
+	group singleflight.Group
+}
+
+// NewRepository caches the reads of next in store for ttl; a ttl of 0 keeps
+// them until a write or the store evicts them.
+func NewRepository(next ireposity.IUserCrud, store Store, ttl time.Duration) ireposity.IUserCrud {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return &userCache{next: next, store: store, ttl: ttl}
+}
+
+func (c *userCache) Insert(ctx context.Context, user entity.User) *objectvalue.Response {
+	return c.invalidate(ctx, c.next.Insert(ctx, user))
+}
+
+func (c *userCache) Delete(ctx context.Context, id uint64) *objectvalue.Response {
+	return c.invalidate(ctx, c.next.Delete(ctx, id))
+}
+
+func (c *userCache) Update(ctx context.Context, user entity.User) *objectvalue.Response {
+	return c.invalidate(ctx, c.next.Update(ctx, user))
+}
+
+func (c *userCache) Upsert(ctx context.Context, users []entity.User) []*objectvalue.Response {
+	results := c.next.Upsert(ctx, users)
+	for _, res := range results {
+		if res.IsOk {
+			c.invalidate(ctx, res)
+			break
+		}
+	}
+	return results
+}
+
+func (c *userCache) Get(ctx context.Context, id uint64) (entity.User, *objectvalue.Response) {
+	user, res := read(c, ctx, fmt.Sprintf("get:%d", id), func(ctx context.Context) (entity.User, *objectvalue.Response) {
+		return c.next.Get(ctx, id)
+	})
+	if !res.IsOk {
+		return entity.User{}, res
+	}
+	return user, objectvalue.Saved(user.ID, user.Version, "")
+}
+
+func (c *userCache) GetMany(ctx context.Context, ids []uint64) ([]entity.User, *objectvalue.Response) {
+	key, err := json.Marshal(ids)
+	if err != nil {
+		return c.next.GetMany(ctx, ids)
+	}
+	return read(c, ctx, "getmany:"+string(key), func(ctx context.Context) ([]entity.User, *objectvalue.Response) {
+		return c.next.GetMany(ctx, ids)
+	})
+}
+
+func (c *userCache) List(ctx context.Context, query ireposity.ListQuery) ([]entity.User, *objectvalue.Response) {
+	key, err := json.Marshal(query)
+	if err != nil {
+		return c.next.List(ctx, query)
+	}
+	return read(c, ctx, "list:"+string(key), func(ctx context.Context) ([]entity.User, *objectvalue.Response) {
+		return c.next.List(ctx, query)
+	})
+}
+
+// Stream is not cached: exports read every user once.
+func (c *userCache) Stream(ctx context.Context, query ireposity.ListQuery, fn func(user entity.User) error) *objectvalue.Response {
+	return c.next.Stream(ctx, query, fn)
+}
+
+func (c *userCache) Search(ctx context.Context, query ireposity.UserQuery) ([]entity.User, *objectvalue.Response) {
+	key, err := json.Marshal(query)
+	if err != nil {
+		return c.next.Search(ctx, query)
+	}
+	return read(c, ctx, "search:"+string(key), func(ctx context.Context) ([]entity.User, *objectvalue.Response) {
+		return c.next.Search(ctx, query)
+	})
+}
+
+// sealedPurpose binds cached values to the cache, as a column name binds
+// the values of an encrypted column.
+const sealedPurpose = "user_cache"
+
+// read returns the cached value of key, loading it on a miss. Concurrent
+// misses on a key share one load, which reads the primary so a lagging
+// replica can't refill the cache with what a write just replaced. The load
+// outlives the caller that started it, so its cancellation fails only its
+// own wait. Callers forcing the primary skip the cache altogether.
+func read[T any](c *userCache, ctx context.Context, key string, load func(ctx context.Context) (T, *objectvalue.Response)) (T, *objectvalue.Response) {
+	if database.PrimaryForced(ctx) {
+		return load(ctx)
+	}
+	scope := scope(ctx)
+	generation, err := c.store.Generation(ctx, scope)
+	if err != nil {
+		c.failed(err)
+		return load(ctx)
+	}
+	kind, detail, _ := strings.Cut(key, ":")
+	if index, err := database.BlindIndex(detail); err == nil {
+		detail = index
+	}
+	key = fmt.Sprintf("%s:%d:%s:%s", scope, generation, kind, detail)
+
+	var value T
+	if cached, ok, err := c.store.Get(ctx, key); err != nil {
+		c.failed(err)
+	} else if ok {
+		if plain, err := database.Unseal(string(cached), sealedPurpose); err != nil {
+			c.failed(err)
+		} else if json.Unmarshal([]byte(plain), &value) == nil {
+			Metrics.Add("hits", 1)
+			return value, objectvalue.Ok(0, "")
+		}
+	}
+
+	Metrics.Add("misses", 1)
+	type loaded struct {
+		value T
+		res   *objectvalue.Response
+	}
+	results := c.group.DoChan(key, func() (interface{}, error) {
+		ctx := detached{ctx}
+		value, res := load(database.WithPrimary(ctx))
+		if res.IsOk {
+			if encoded, err := json.Marshal(value); err != nil {
+				c.failed(err)
+			} else if sealed, err := database.Seal(string(encoded), sealedPurpose); err != nil {
+				c.failed(err)
+			} else if err := c.store.Set(ctx, key, []byte(sealed), c.ttl); err != nil {
+				c.failed(err)
+			}
+		}
+		return loaded{value: value, res: res}, nil
+	})
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, objectvalue.Fail(status.FromContextError(ctx.Err()).Code(), ctx.Err().Error())
+	case result := <-results:
+		if result.Shared {
+			Metrics.Add("shared", 1)
+		}
+		return result.Val.(loaded).value, result.Val.(loaded).res
+	}
+}
+
+// detached keeps the values of a context but not its deadline or
+// cancellation, as context.WithoutCancel does from Go 1.21 on.
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
+func (c *userCache) invalidate(ctx context.Context, res *objectvalue.Response) *objectvalue.Response {
+	if res.IsOk {
+		database.AfterCommit(ctx, func() {
+			if err := c.store.Bump(ctx, scope(ctx)); err != nil {
+				c.failed(err)
+			}
+		})
+	}
+	return res
+}
+
+func (c *userCache) failed(err error) {
+	Metrics.Add("errors", 1)
+	log.Printf("user cache: %v", err)
+}
+
+func scope(ctx context.Context) string {
+	return "users:" + identity.FromContext(ctx).Tenant
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+)
+
+type auditLog struct {
+	mu     sync.RWMutex
+	events []entity.AuditEvent
+}
+
+func NewAuditLog() ireposity.IAuditLog {
+	return &auditLog{}
+}
+
+func (a *auditLog) Record(ctx context.Context, event entity.AuditEvent) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	event.ID = uint64(len(a.events) + 1)
+	event.TenantID = identity.FromContext(ctx).Tenant
+	a.events = append(a.events, event)
+	return nil
+}
+
+// List returns events in the order they were recorded, which is also
+// creation time order.
+func (a *auditLog) List(ctx context.Context, filter ireposity.AuditFilter) ([]entity.AuditEvent, *objectvalue.Response) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	tenant := identity.FromContext(ctx).Tenant
+	var matched []entity.AuditEvent
+	for _, event := range a.events {
+		switch {
+		case event.TenantID != tenant,
+			filter.Entity != "" && event.Entity != filter.Entity,
+			filter.EntityID != 0 && event.EntityID != filter.EntityID,
+			filter.Actor != "" && event.Actor != filter.Actor,
+			!filter.From.IsZero() && event.CreatedAt.Before(filter.From),
+			!filter.To.IsZero() && !event.CreatedAt.Before(filter.To):
+			continue
+		}
+		matched = append(matched, event)
+	}
+	return page(matched, filter.Offset, filter.Limit), objectvalue.Ok(0, "")
+}
+
+// page applies List's offset and limit to items already in order.
+func page[T any](items []T, offset, limit int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(items) {
+		offset = len(items)
+	}
+	end := offset + ireposity.PageSize(limit)
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
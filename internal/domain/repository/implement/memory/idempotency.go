@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+type idempotencyKey struct {
+	tenant, key string
+}
+
+type idempotencyStore struct {
+	mu   sync.Mutex
+	keys map[idempotencyKey]entity.IdempotencyKey
+}
+
+func NewIdempotencyStore() ireposity.IIdempotencyStore {
+	return &idempotencyStore{keys: map[idempotencyKey]entity.IdempotencyKey{}}
+}
+
+func (s *idempotencyStore) Begin(ctx context.Context, key, hash string, lease time.Duration) (string, *objectvalue.Response, *objectvalue.Response) {
+	token, err := ireposity.NewLeaseToken()
+	if err != nil {
+		return "", nil, objectvalue.Fail(codes.Internal, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	tenant := identity.FromContext(ctx).Tenant
+	for k, claimed := range s.keys {
+		if k.tenant == tenant && !claimed.ExpiresAt.After(now) {
+			delete(s.keys, k)
+		}
+	}
+
+	k := idempotencyKey{tenant: tenant, key: key}
+	if claimed, ok := s.keys[k]; ok {
+		replay, res := ireposity.Replay(claimed, hash)
+		return "", replay, res
+	}
+	s.keys[k] = entity.IdempotencyKey{
+		TenantID:  tenant,
+		Key:       key,
+		Hash:      hash,
+		Token:     token,
+		ExpiresAt: now.Add(lease),
+		CreatedAt: now,
+	}
+	return token, nil, objectvalue.Ok(0, "")
+}
+
+func (s *idempotencyStore) Finish(ctx context.Context, key, token string, response *objectvalue.Response, ttl time.Duration) *objectvalue.Response {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return objectvalue.Fail(codes.Internal, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{tenant: identity.FromContext(ctx).Tenant, key: key}
+	claimed, ok := s.keys[k]
+	if !ok || claimed.Token != token || claimed.Response != "" {
+		return objectvalue.Fail(codes.Aborted, ireposity.MessageIdempotencyLeaseLost)
+	}
+	claimed.Response = string(encoded)
+	claimed.ExpiresAt = time.Now().UTC().Add(ttl)
+	s.keys[k] = claimed
+	return objectvalue.Ok(0, "")
+}
+
+func (s *idempotencyStore) Abandon(ctx context.Context, key, token string) *objectvalue.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{tenant: identity.FromContext(ctx).Tenant, key: key}
+	if claimed, ok := s.keys[k]; ok && claimed.Token == token && claimed.Response == "" {
+		delete(s.keys, k)
+	}
+	return objectvalue.Ok(0, "")
+}
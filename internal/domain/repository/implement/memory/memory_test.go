@@ -0,0 +1,41 @@
+package memory_test
+
+import (
+	"template-grpc/internal/domain/repository/conformance"
+	"template-grpc/internal/domain/repository/implement/memory"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"testing"
+)
+
+func TestUserCrud(t *testing.T) {
+	conformance.UserCrud(t, func(t *testing.T) ireposity.IUserCrud {
+		return memory.NewRepository(memory.NewAuditLog())
+	})
+}
+
+func TestAuditLog(t *testing.T) {
+	conformance.AuditLog(t, func(t *testing.T) (ireposity.IUserCrud, ireposity.IAuditLog) {
+		auditLog := memory.NewAuditLog()
+		return memory.NewRepository(auditLog), auditLog
+	})
+}
+
+func TestTenancy(t *testing.T) {
+	conformance.Tenancy(t, func(t *testing.T) (ireposity.IUserCrud, ireposity.IAuditLog) {
+		auditLog := memory.NewAuditLog()
+		return memory.NewRepository(auditLog), auditLog
+	})
+}
+
+func TestUserFeed(t *testing.T) {
+	conformance.UserFeed(t, func(t *testing.T) (ireposity.IUserCrud, ireposity.IUserFeed) {
+		users := memory.NewRepository(memory.NewAuditLog())
+		return users, memory.NewFeed(users)
+	})
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	conformance.IdempotencyStore(t, func(t *testing.T) ireposity.IIdempotencyStore {
+		return memory.NewIdempotencyStore()
+	})
+}
@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	ireposity "template-grpc/internal/domain/repository/interface"
+)
+
+type unitOfWorkKey struct{}
+
+type unitOfWork struct {
+	mu    sync.Mutex
+	users ireposity.IUserCrud
+}
+
+// NewUnitOfWork runs units of work against users one at a time. Nothing is
+// rolled back: a failed unit keeps the writes it made before failing.
+func NewUnitOfWork(users ireposity.IUserCrud) ireposity.IUnitOfWork {
+	return &unitOfWork{users: users}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos ireposity.IRepositories) error) error {
+	if ctx.Value(unitOfWorkKey{}) == nil {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		ctx = context.WithValue(ctx, unitOfWorkKey{}, true)
+	}
+	return fn(ctx, repositories{users: u.users})
+}
+
+type repositories struct {
+	users ireposity.IUserCrud
+}
+
+func (r repositories) Users() ireposity.IUserCrud {
+	return r.users
+}
@@ -0,0 +1,378 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/filter"
+	"template-grpc/internal/domain/identity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// userCrud keeps users in memory and mirrors the GORM repository: sequential
+// IDs, a document unique per tenant, versions, soft deletes and every call
+// scoped to the tenant of its context. It also keeps every change, serving
+// as its own feed.
+type userCrud struct {
+	mu      sync.RWMutex
+	nextID  uint64
+	users   map[uint64]entity.User
+	audit   ireposity.IAuditLog
+	changes []ireposity.UserChange
+}
+
+// NewRepository records every change in auditLog.
+func NewRepository(auditLog ireposity.IAuditLog) ireposity.IUserCrud {
+	return &userCrud{users: map[uint64]entity.User{}, audit: auditLog}
+}
+
+func (u *userCrud) Insert(ctx context.Context, user entity.User) *objectvalue.Response {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	tenant := identity.FromContext(ctx).Tenant
+	if u.documentTaken(tenant, user.Document, 0) {
+		return objectvalue.Fail(codes.AlreadyExists, ireposity.MessageDuplicateDocument)
+	}
+	now := time.Now().UTC()
+	actor := identity.Actor(ctx)
+	u.nextID++
+	user.ID = u.nextID
+	user.TenantID = tenant
+	user.Version = 1
+	user.CreatedAt, user.UpdatedAt = now, now
+	user.CreatedBy, user.UpdatedBy = actor, actor
+	user.NameSearch = entity.NormalizeName(user.Name)
+	user.DeletedAt = gorm.DeletedAt{}
+	if err := u.record(ctx, user.ID, entity.ActionInsert, nil, user.AuditFields()); err != nil {
+		return objectvalue.Fail(codes.Internal, err.Error())
+	}
+	u.users[user.ID] = user
+	u.announce(ctx, entity.EventUserCreated, user)
+	return objectvalue.Saved(user.ID, user.Version, "Usuario creado")
+}
+
+func (u *userCrud) Delete(ctx context.Context, id uint64) *objectvalue.Response {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	user, ok := u.active(ctx, id)
+	if !ok {
+		return objectvalue.Fail(codes.NotFound, ireposity.MessageNotFound)
+	}
+	if err := u.record(ctx, user.ID, entity.ActionDelete, user.AuditFields(), nil); err != nil {
+		return objectvalue.Fail(codes.Internal, err.Error())
+	}
+	u.announce(ctx, entity.EventUserDeleted, user)
+	user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	u.users[user.ID] = user
+	return objectvalue.Ok(user.ID, "Usuario eliminado")
+}
+
+func (u *userCrud) Update(ctx context.Context, user entity.User) *objectvalue.Response {
+	if user.ID == 0 {
+		return objectvalue.Fail(codes.InvalidArgument, ireposity.MessageIDRequired)
+	}
+	if user.Version == 0 {
+		return objectvalue.Fail(codes.InvalidArgument, ireposity.MessageVersionRequired)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	current, ok := u.active(ctx, user.ID)
+	if !ok {
+		return objectvalue.Fail(codes.NotFound, ireposity.MessageNotFound)
+	}
+	if current.Version != user.Version {
+		return objectvalue.Fail(codes.Aborted, ireposity.MessageVersionMismatch)
+	}
+	if u.documentTaken(current.TenantID, user.Document, user.ID) {
+		return objectvalue.Fail(codes.AlreadyExists, ireposity.MessageDuplicateDocument)
+	}
+	before := current.AuditFields()
+	current.Name = user.Name
+	current.NameSearch = entity.NormalizeName(user.Name)
+	current.Document = user.Document
+	current.Phone = user.Phone
+	current.Version++
+	current.UpdatedAt = time.Now().UTC()
+	current.UpdatedBy = identity.Actor(ctx)
+	if err := u.record(ctx, current.ID, entity.ActionUpdate, before, current.AuditFields()); err != nil {
+		return objectvalue.Fail(codes.Internal, err.Error())
+	}
+	u.users[current.ID] = current
+	u.announce(ctx, entity.EventUserUpdated, current)
+	return objectvalue.Saved(current.ID, current.Version, "Usuario actualizado")
+}
+
+// Upsert writes users one by one: unlike the GORM repository, a batch is
+// not atomic.
+func (u *userCrud) Upsert(ctx context.Context, users []entity.User) []*objectvalue.Response {
+	results := make([]*objectvalue.Response, len(users))
+	for i, user := range users {
+		u.mu.RLock()
+		current, found := u.byDocument(ctx, user.Document)
+		u.mu.RUnlock()
+		if found {
+			user.ID, user.Version = current.ID, current.Version
+			results[i] = u.Update(ctx, user)
+		} else {
+			results[i] = u.Insert(ctx, user)
+		}
+	}
+	return results
+}
+
+func (u *userCrud) Get(ctx context.Context, id uint64) (entity.User, *objectvalue.Response) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	user, ok := u.active(ctx, id)
+	if !ok {
+		return entity.User{}, objectvalue.Fail(codes.NotFound, ireposity.MessageNotFound)
+	}
+	return user, objectvalue.Saved(user.ID, user.Version, "")
+}
+
+func (u *userCrud) GetMany(ctx context.Context, ids []uint64) ([]entity.User, *objectvalue.Response) {
+	if len(ids) > ireposity.MaxBatchGet {
+		return nil, objectvalue.Fail(codes.InvalidArgument, ireposity.MessageTooManyIDs)
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	users := []entity.User{}
+	seen := map[uint64]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if user, ok := u.active(ctx, id); ok {
+			users = append(users, user)
+		}
+	}
+	return users, objectvalue.Ok(0, "")
+}
+
+func (u *userCrud) List(ctx context.Context, query ireposity.ListQuery) ([]entity.User, *objectvalue.Response) {
+	expr, orders, message := query.Parse()
+	if message != "" {
+		return nil, objectvalue.Fail(codes.InvalidArgument, message)
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return page(u.matching(ctx, expr, orders), query.Offset, query.Limit), objectvalue.Ok(0, "")
+}
+
+// Stream hands fn a snapshot taken when it starts, so fn may call the
+// repository.
+func (u *userCrud) Stream(ctx context.Context, query ireposity.ListQuery, fn func(user entity.User) error) *objectvalue.Response {
+	expr, orders, message := query.Parse()
+	if message != "" {
+		return objectvalue.Fail(codes.InvalidArgument, message)
+	}
+
+	u.mu.RLock()
+	users := u.matching(ctx, expr, orders)
+	u.mu.RUnlock()
+
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return objectvalue.Fail(status.FromContextError(err).Code(), err.Error())
+		}
+		if err := fn(user); err != nil {
+			return objectvalue.Fail(codes.Internal, err.Error())
+		}
+	}
+	return objectvalue.Ok(0, "")
+}
+
+func (u *userCrud) Search(ctx context.Context, query ireposity.UserQuery) ([]entity.User, *objectvalue.Response) {
+	if message := query.Normalize(); message != "" {
+		return nil, objectvalue.Fail(codes.InvalidArgument, message)
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	name := entity.NormalizeName(query.Name)
+	var matched []entity.User
+	for _, user := range u.visible(ctx) {
+		if matches(user.NameSearch, name, query.NameMatch) &&
+			matches(user.Document, query.Document, query.DocumentMatch) &&
+			matches(user.Phone, query.Phone, query.PhoneMatch) {
+			matched = append(matched, user)
+		}
+	}
+	return page(matched, query.Offset, query.Limit), objectvalue.Ok(0, "")
+}
+
+// NewFeed returns the changes kept by users, which must come from
+// NewRepository.
+func NewFeed(users ireposity.IUserCrud) ireposity.IUserFeed {
+	return users.(*userCrud)
+}
+
+func (u *userCrud) Changes(ctx context.Context, after uint64, limit int) ([]ireposity.UserChange, *objectvalue.Response) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	if after > uint64(len(u.changes)) {
+		after = uint64(len(u.changes))
+	}
+	tenant := identity.FromContext(ctx).Tenant
+	var changes []ireposity.UserChange
+	for _, change := range u.changes[after:] {
+		if len(changes) == limit {
+			break
+		}
+		if change.User.Tenant == tenant {
+			changes = append(changes, change)
+		}
+	}
+	return changes, objectvalue.Ok(0, "")
+}
+
+func (u *userCrud) Head(ctx context.Context) (uint64, *objectvalue.Response) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	tenant := identity.FromContext(ctx).Tenant
+	for i := len(u.changes) - 1; i >= 0; i-- {
+		if u.changes[i].User.Tenant == tenant {
+			return u.changes[i].Token, objectvalue.Ok(0, "")
+		}
+	}
+	return 0, objectvalue.Ok(0, "")
+}
+
+// announce keeps a change; tokens are positions in u.changes counted from 1.
+func (u *userCrud) announce(ctx context.Context, eventType string, user entity.User) {
+	u.changes = append(u.changes, ireposity.UserChange{
+		Token: uint64(len(u.changes) + 1),
+		Type:  eventType,
+		User: entity.UserEvent{
+			ID:         user.ID,
+			Tenant:     user.TenantID,
+			Name:       user.Name,
+			Document:   user.Document,
+			Phone:      user.Phone,
+			Version:    user.Version,
+			Actor:      identity.Actor(ctx),
+			OccurredAt: time.Now().UTC(),
+		},
+	})
+}
+
+func (u *userCrud) record(ctx context.Context, id uint64, action string, before, after map[string]interface{}) error {
+	event, err := entity.NewAuditEvent("users", id, action, identity.Actor(ctx), before, after)
+	if err != nil {
+		return err
+	}
+	return u.audit.Record(ctx, event)
+}
+
+func matches(value, criterion string, match ireposity.Match) bool {
+	switch {
+	case criterion == "":
+		return true
+	case match == ireposity.MatchExact:
+		return value == criterion
+	case match == ireposity.MatchPrefix:
+		return strings.HasPrefix(value, criterion)
+	}
+	return strings.Contains(value, criterion)
+}
+
+// matching returns the visible users matching expr, sorted by orders.
+func (u *userCrud) matching(ctx context.Context, expr filter.Expr, orders []filter.Order) []entity.User {
+	var matched []entity.User
+	for _, user := range u.visible(ctx) {
+		if filter.Eval(expr, func(field string) interface{} { return fieldValue(user, field) }) {
+			matched = append(matched, user)
+		}
+	}
+	sort.SliceStable(matched, filter.Less(orders, func(i int, field string) interface{} {
+		return fieldValue(matched[i], field)
+	}))
+	return matched
+}
+
+// fieldValue returns a field of ireposity.UserFields the way the GORM
+// repository compares it.
+func fieldValue(user entity.User, field string) interface{} {
+	switch field {
+	case "id":
+		return user.ID
+	case "name":
+		return user.NameSearch
+	case "document":
+		return user.Document
+	case "phone":
+		return user.Phone
+	case "version":
+		return user.Version
+	case "created_at":
+		return user.CreatedAt
+	case "updated_at":
+		return user.UpdatedAt
+	case "created_by":
+		return user.CreatedBy
+	case "updated_by":
+		return user.UpdatedBy
+	}
+	return nil
+}
+
+// visible returns the caller's tenant users that are not deleted, by id.
+func (u *userCrud) visible(ctx context.Context) []entity.User {
+	tenant := identity.FromContext(ctx).Tenant
+	users := make([]entity.User, 0, len(u.users))
+	for _, user := range u.users {
+		if user.TenantID == tenant && !user.DeletedAt.Valid {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+// byDocument finds the caller's tenant user with document that is not
+// deleted.
+func (u *userCrud) byDocument(ctx context.Context, document string) (entity.User, bool) {
+	tenant := identity.FromContext(ctx).Tenant
+	for _, user := range u.users {
+		if user.TenantID == tenant && user.Document == document && !user.DeletedAt.Valid {
+			return user, true
+		}
+	}
+	return entity.User{}, false
+}
+
+// active finds a user that is not deleted and belongs to the caller's tenant.
+func (u *userCrud) active(ctx context.Context, id uint64) (entity.User, bool) {
+	user, ok := u.users[id]
+	return user, ok && user.TenantID == identity.FromContext(ctx).Tenant && !user.DeletedAt.Valid
+}
+
+// documentTaken checks deleted users too, as the unique index does.
+func (u *userCrud) documentTaken(tenant, document string, except uint64) bool {
+	for id, user := range u.users {
+		if id != except && user.TenantID == tenant && user.Document == document {
+			return true
+		}
+	}
+	return false
+}
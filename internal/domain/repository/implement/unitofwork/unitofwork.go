@@ -0,0 +1,30 @@
+package unitofwork
+
+import (
+	"context"
+	repository "template-grpc/internal/domain/repository/implement/user"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+)
+
+type unitOfWork struct {
+	transactor *database.Transactor
+}
+
+func NewUnitOfWork(db database.Resolver, maxRetries int) ireposity.IUnitOfWork {
+	return &unitOfWork{transactor: database.NewTransactor(db, maxRetries)}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos ireposity.IRepositories) error) error {
+	return u.transactor.Transaction(ctx, func(ctx context.Context, tx database.Resolver) error {
+		return fn(ctx, repositories{db: tx})
+	})
+}
+
+type repositories struct {
+	db database.Resolver
+}
+
+func (r repositories) Users() ireposity.IUserCrud {
+	return repository.NewRepository(r.db)
+}
@@ -0,0 +1,84 @@
+package unitofwork_test
+
+import (
+	"context"
+	"errors"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/repository/implement/unitofwork"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+	"template-grpc/internal/infra/database/dbtest"
+	"testing"
+)
+
+// TestRetriesALockConflict holds the users table in another transaction, so
+// the first attempt's insert fails with a lock error; the unit of work sees
+// it through the repository's response and runs again once it is released.
+func TestRetriesALockConflict(t *testing.T) {
+	db := dbtest.Open(t)
+	uow := unitofwork.NewUnitOfWork(database.NewCluster(db, nil, database.Random), database.DefaultRetries)
+
+	blocker := db.Begin()
+	if err := blocker.Exec("UPDATE users SET name = name").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := 0
+	var id uint64
+	err := uow.Do(context.Background(), func(ctx context.Context, repos ireposity.IRepositories) error {
+		attempts++
+		res := repos.Users().Insert(ctx, entity.User{Name: "Ada", Document: "1001", Phone: "555-1001"})
+		if attempts == 1 {
+			blocker.Rollback()
+			if res.IsOk {
+				t.Fatal("the first attempt wrote through the lock")
+			}
+			if !database.Retryable(res.Err()) {
+				t.Fatalf("the failure doesn't carry its cause: %v", res.Err())
+			}
+		}
+		id = res.ID
+		return res.Err()
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("ran %d times, want 2", attempts)
+	}
+
+	var user entity.User
+	if err := db.First(&user, id).Error; err != nil {
+		t.Fatalf("the retried insert was not committed: %v", err)
+	}
+	var events int64
+	if err := db.Model(&entity.AuditEvent{}).Where("entity_id = ?", id).Count(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if events != 1 {
+		t.Fatalf("%d audit events for the user, want the retry's only", events)
+	}
+}
+
+func TestRollsBackAFailedUnit(t *testing.T) {
+	db := dbtest.Open(t)
+	uow := unitofwork.NewUnitOfWork(database.NewCluster(db, nil, database.Random), database.DefaultRetries)
+
+	abort := errors.New("abort")
+	err := uow.Do(context.Background(), func(ctx context.Context, repos ireposity.IRepositories) error {
+		if res := repos.Users().Insert(ctx, entity.User{Name: "Ada", Document: "1001"}); !res.IsOk {
+			t.Fatalf("insert: %s", res.Message)
+		}
+		return abort
+	})
+	if !errors.Is(err, abort) {
+		t.Fatalf("Do: got %v, want %v", err, abort)
+	}
+	var users int64
+	if err := db.Unscoped().Model(&entity.User{}).Count(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if users != 0 {
+		t.Fatalf("%d users left after the rollback", users)
+	}
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"gorm.io/gorm"
+)
+
+// DefaultSettle is how old an outbox event must be before a feed shows it.
+const DefaultSettle = time.Second
+
+// userFeed reads the user events queued in the outbox, which keeps them
+// after they are published. Outbox ids are taken when a transaction inserts
+// its event, not when it commits, so a feed only shows events older than
+// settle: as long as transactions finish within settle, one holding a lower
+// id has committed or rolled back by then and a watcher past a later id
+// can't miss it.
+type userFeed struct {
+	db     database.Resolver
+	settle time.Duration
+}
+
+func NewFeed(db database.Resolver, settle time.Duration) ireposity.IUserFeed {
+	if settle <= 0 {
+		settle = DefaultSettle
+	}
+	return &userFeed{db: db, settle: settle}
+}
+
+func (f *userFeed) Changes(ctx context.Context, after uint64, limit int) ([]ireposity.UserChange, *objectvalue.Response) {
+	if res := f.retained(ctx, after); !res.IsOk {
+		return nil, res
+	}
+	find, err := f.events(ctx)
+	if err != nil {
+		return nil, failed(err)
+	}
+	var events []entity.OutboxEvent
+	err = find.
+		Where("id > ?", after).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, failed(err)
+	}
+
+	changes := make([]ireposity.UserChange, 0, len(events))
+	for _, event := range events {
+		change := ireposity.UserChange{Token: event.ID, Type: event.Type}
+		if err := json.Unmarshal([]byte(event.Payload), &change.User); err != nil {
+			return nil, failed(err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, objectvalue.Ok(0, "")
+}
+
+func (f *userFeed) Head(ctx context.Context) (uint64, *objectvalue.Response) {
+	find, err := f.events(ctx)
+	if err != nil {
+		return 0, failed(err)
+	}
+	var head uint64
+	if err := find.Select("COALESCE(MAX(id), 0)").Scan(&head).Error; err != nil {
+		return 0, failed(err)
+	}
+	return head, objectvalue.Ok(0, "")
+}
+
+// retained fails once events right after token were pruned. Pruning drops
+// the oldest ids of every tenant, so a token is only good while the next id
+// is still there, or was never taken.
+func (f *userFeed) retained(ctx context.Context, after uint64) *objectvalue.Response {
+	if after == 0 {
+		return objectvalue.Ok(0, "")
+	}
+	var oldest uint64
+	err := f.db.Writer(ctx).
+		Model(&entity.OutboxEvent{}).
+		Select("COALESCE(MIN(id), 0)").
+		Scan(&oldest).Error
+	if err != nil {
+		return failed(err)
+	}
+	if after+1 < oldest {
+		return objectvalue.Fail(codes.OutOfRange, ireposity.MessageTokenExpired)
+	}
+	return objectvalue.Ok(0, "")
+}
+
+// events selects the caller's tenant user events old enough to be shown.
+// They are read from the primary, since a lagging replica would let a
+// watcher step over events it hasn't received yet, and settled against the
+// database clock they were dated with. The outbox isn't scoped by the
+// tenancy plugin, so the tenant is explicit.
+func (f *userFeed) events(ctx context.Context) (*gorm.DB, error) {
+	db := f.db.Writer(ctx)
+	now, err := database.Now(db)
+	if err != nil {
+		return nil, err
+	}
+	return db.Model(&entity.OutboxEvent{}).
+		Where("tenant = ? AND topic = ? AND created_at <= ?",
+			identity.FromContext(ctx).Tenant, entity.TopicUsers, now.Add(-f.settle)), nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"template-grpc/internal/domain/entity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"gorm.io/gorm"
+)
+
+// idempotencyStore keeps idempotency keys in the primary, scoped to the
+// tenant by the tenancy plugin. The primary key on tenant and key lets only
+// one of several concurrent requests claim it.
+type idempotencyStore struct {
+	db database.Resolver
+}
+
+func NewIdempotencyStore(db database.Resolver) ireposity.IIdempotencyStore {
+	return &idempotencyStore{db: db}
+}
+
+func (s *idempotencyStore) Begin(ctx context.Context, key, hash string, lease time.Duration) (string, *objectvalue.Response, *objectvalue.Response) {
+	now := time.Now().UTC()
+	db := s.db.Writer(ctx)
+	if err := db.Where("expires_at <= ?", now).Delete(&entity.IdempotencyKey{}).Error; err != nil {
+		return "", nil, failed(err)
+	}
+
+	token, err := ireposity.NewLeaseToken()
+	if err != nil {
+		return "", nil, failed(err)
+	}
+	err = db.Create(&entity.IdempotencyKey{Key: key, Hash: hash, Token: token, ExpiresAt: now.Add(lease), CreatedAt: now}).Error
+	if err == nil {
+		return token, nil, objectvalue.Ok(0, "")
+	}
+	if !duplicated(err) {
+		return "", nil, failed(err)
+	}
+
+	var claimed entity.IdempotencyKey
+	if err := db.Where("idempotency_key = ?", key).First(&claimed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Abandoned since the claim failed; the caller may retry.
+			return "", nil, objectvalue.Fail(codes.Aborted, ireposity.MessageIdempotencyInProgress)
+		}
+		return "", nil, failed(err)
+	}
+	replay, res := ireposity.Replay(claimed, hash)
+	return "", replay, res
+}
+
+func (s *idempotencyStore) Finish(ctx context.Context, key, token string, response *objectvalue.Response, ttl time.Duration) *objectvalue.Response {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return failed(err)
+	}
+	result := s.db.Writer(ctx).
+		Model(&entity.IdempotencyKey{}).
+		Where("idempotency_key = ? AND lease_token = ? AND response = ''", key, token).
+		Updates(map[string]interface{}{
+			"response":   string(encoded),
+			"expires_at": time.Now().UTC().Add(ttl),
+		})
+	if result.Error != nil {
+		return failed(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return objectvalue.Fail(codes.Aborted, ireposity.MessageIdempotencyLeaseLost)
+	}
+	return objectvalue.Ok(0, "")
+}
+
+func (s *idempotencyStore) Abandon(ctx context.Context, key, token string) *objectvalue.Response {
+	err := s.db.Writer(ctx).
+		Where("idempotency_key = ? AND lease_token = ? AND response = ''", key, token).
+		Delete(&entity.IdempotencyKey{}).Error
+	if err != nil {
+		return failed(err)
+	}
+	return objectvalue.Ok(0, "")
+}
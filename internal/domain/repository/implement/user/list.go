@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/filter"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+
+	"google.golang.org/grpc/codes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// columns maps the fields of ireposity.UserFields to the columns they are
+// compared and sorted by; documents and phones go through blind indexes.
+var columns = map[string]string{
+	"id":         "id",
+	"name":       "name_search",
+	"document":   "document_hash",
+	"phone":      "phone_hash",
+	"version":    "version",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"created_by": "created_by",
+	"updated_by": "updated_by",
+}
+
+var sqlOps = map[filter.Op]string{
+	filter.Eq: "=",
+	filter.Ne: "<>",
+	filter.Lt: "<",
+	filter.Le: "<=",
+	filter.Gt: ">",
+	filter.Ge: ">=",
+}
+
+func (u *userCrud) List(ctx context.Context, query ireposity.ListQuery) ([]entity.User, *objectvalue.Response) {
+	find, res := u.find(ctx, query)
+	if res != nil {
+		return nil, res
+	}
+	var users []entity.User
+	err := find.
+		Offset(query.Offset).
+		Limit(ireposity.PageSize(query.Limit)).
+		Find(&users).Error
+	if err != nil {
+		return nil, failed(err)
+	}
+	return users, objectvalue.Ok(0, "")
+}
+
+func (u *userCrud) Stream(ctx context.Context, query ireposity.ListQuery, fn func(user entity.User) error) *objectvalue.Response {
+	find, res := u.find(ctx, query)
+	if res != nil {
+		return res
+	}
+	rows, err := find.Rows()
+	if err != nil {
+		return failed(err)
+	}
+	defer rows.Close()
+
+	scan := u.db.Reader(ctx)
+	for rows.Next() {
+		var user entity.User
+		if err := scan.ScanRows(rows, &user); err != nil {
+			return failed(err)
+		}
+		if err := fn(user); err != nil {
+			return failed(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return failed(err)
+	}
+	return objectvalue.Ok(0, "")
+}
+
+// find prepares the query selecting and sorting the users of a ListQuery.
+func (u *userCrud) find(ctx context.Context, query ireposity.ListQuery) (*gorm.DB, *objectvalue.Response) {
+	expr, orders, message := query.Parse()
+	if message != "" {
+		return nil, objectvalue.Fail(codes.InvalidArgument, message)
+	}
+
+	db := u.db.Reader(ctx)
+	find := db.Model(&entity.User{})
+	if expr != nil {
+		cond, err := where(db, expr)
+		if err != nil {
+			return nil, failed(err)
+		}
+		find = find.Where(cond)
+	}
+	orderBy := clause.OrderBy{}
+	for _, order := range orders {
+		orderBy.Columns = append(orderBy.Columns, clause.OrderByColumn{
+			Column: clause.Column{Name: columns[order.Field]},
+			Desc:   order.Desc,
+		})
+	}
+	return find.Clauses(orderBy), nil
+}
+
+// where translates a parsed filter into a clause. Only whitelisted column
+// names reach the SQL; every value is a bind variable.
+func where(db *gorm.DB, expr filter.Expr) (clause.Expr, error) {
+	switch e := expr.(type) {
+	case filter.And:
+		return join("(? AND ?)", db, e.Left, e.Right)
+	case filter.Or:
+		return join("(? OR ?)", db, e.Left, e.Right)
+	case filter.Not:
+		return join("NOT (?)", db, e.Expr)
+	}
+
+	c := expr.(filter.Compare)
+	column := clause.Column{Name: columns[c.Field]}
+	if c.Field == fieldDocument || c.Field == fieldPhone {
+		value := c.Value.(string)
+		if !c.Prefix {
+			hash, err := database.BlindIndex(value)
+			if err != nil {
+				return clause.Expr{}, err
+			}
+			return clause.Expr{SQL: "? " + sqlOps[c.Op] + " ?", Vars: []interface{}{column, hash}}, nil
+		}
+		token, err := prefixToken(c.Field, value)
+		if err != nil {
+			return clause.Expr{}, err
+		}
+		tokens := db.Session(&gorm.Session{NewDB: true}).
+			Model(&entity.UserSearchToken{}).
+			Select("user_id").
+			Where("field = ? AND token = ?", c.Field, token)
+		in := "IN"
+		if c.Op == filter.Ne {
+			in = "NOT IN"
+		}
+		return clause.Expr{SQL: "? " + in + " (?)", Vars: []interface{}{clause.Column{Name: "id"}, tokens}}, nil
+	}
+
+	switch {
+	case c.Prefix:
+		like := "LIKE"
+		if c.Op == filter.Ne {
+			like = "NOT LIKE"
+		}
+		pattern := escapeLike(c.Value.(string)) + "%"
+		return clause.Expr{SQL: "? " + like + " ? ESCAPE '!'", Vars: []interface{}{column, pattern}}, nil
+	case c.Op == filter.Has:
+		pattern := "%" + escapeLike(c.Value.(string)) + "%"
+		return clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []interface{}{column, pattern}}, nil
+	}
+	return clause.Expr{SQL: "? " + sqlOps[c.Op] + " ?", Vars: []interface{}{column, c.Value}}, nil
+}
+
+// join fills sql with the clauses of the given operands.
+func join(sql string, db *gorm.DB, operands ...filter.Expr) (clause.Expr, error) {
+	vars := make([]interface{}, len(operands))
+	for i, operand := range operands {
+		expr, err := where(db, operand)
+		if err != nil {
+			return clause.Expr{}, err
+		}
+		vars[i] = expr
+	}
+	return clause.Expr{SQL: sql, Vars: vars}, nil
+}
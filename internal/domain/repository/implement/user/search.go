@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"template-grpc/internal/domain/entity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+	"gorm.io/gorm"
+)
+
+const (
+	fieldDocument = "document"
+	fieldPhone    = "phone"
+)
+
+func (u *userCrud) Search(ctx context.Context, query ireposity.UserQuery) ([]entity.User, *objectvalue.Response) {
+	if message := query.Normalize(); message != "" {
+		return nil, objectvalue.Fail(codes.InvalidArgument, message)
+	}
+
+	db := u.db.Reader(ctx)
+	find := db.Model(&entity.User{})
+	if query.Name != "" {
+		name := entity.NormalizeName(query.Name)
+		switch query.NameMatch {
+		case ireposity.MatchExact:
+			find = find.Where("name_search = ?", name)
+		case ireposity.MatchPrefix:
+			find = find.Where("name_search LIKE ? ESCAPE '!'", escapeLike(name)+"%")
+		default:
+			find = find.Where("name_search LIKE ? ESCAPE '!'", "%"+escapeLike(name)+"%")
+		}
+	}
+	find, err := matchEncrypted(db, find, fieldDocument, "document_hash", query.Document, query.DocumentMatch)
+	if err != nil {
+		return nil, failed(err)
+	}
+	find, err = matchEncrypted(db, find, fieldPhone, "phone_hash", query.Phone, query.PhoneMatch)
+	if err != nil {
+		return nil, failed(err)
+	}
+
+	var users []entity.User
+	err = find.
+		Order("id").
+		Offset(query.Offset).
+		Limit(ireposity.PageSize(query.Limit)).
+		Find(&users).Error
+	if err != nil {
+		return nil, failed(err)
+	}
+	return users, objectvalue.Ok(0, "")
+}
+
+// matchEncrypted compares an encrypted field through its blind index, or
+// through the blind indexes of its prefixes.
+func matchEncrypted(db, find *gorm.DB, field, hashColumn, value string, match ireposity.Match) (*gorm.DB, error) {
+	if value == "" {
+		return find, nil
+	}
+	if match == ireposity.MatchExact {
+		hash, err := database.BlindIndex(value)
+		if err != nil {
+			return nil, err
+		}
+		return find.Where(hashColumn+" = ?", hash), nil
+	}
+	token, err := prefixToken(field, value)
+	if err != nil {
+		return nil, err
+	}
+	tokens := db.Session(&gorm.Session{NewDB: true}).
+		Model(&entity.UserSearchToken{}).
+		Select("user_id").
+		Where("field = ? AND token = ?", field, token)
+	return find.Where("id IN (?)", tokens), nil
+}
+
+// Index fills the columns derived from a user's name, document and phone.
+// Everything writing users calls it, then SaveSearchTokens once the user
+// has an id.
+func Index(user *entity.User) error {
+	var err error
+	if user.DocumentHash, err = database.BlindIndex(user.Document); err != nil {
+		return err
+	}
+	if user.PhoneHash, err = database.BlindIndex(user.Phone); err != nil {
+		return err
+	}
+	user.NameSearch = entity.NormalizeName(user.Name)
+	return nil
+}
+
+// SaveSearchTokens replaces the prefix tokens of a user.
+func SaveSearchTokens(db *gorm.DB, user entity.User) error {
+	if err := db.Where("user_id = ?", user.ID).Delete(&entity.UserSearchToken{}).Error; err != nil {
+		return err
+	}
+	var tokens []entity.UserSearchToken
+	for field, value := range map[string]string{fieldDocument: user.Document, fieldPhone: user.Phone} {
+		for _, prefix := range prefixes(value) {
+			token, err := prefixToken(field, prefix)
+			if err != nil {
+				return err
+			}
+			tokens = append(tokens, entity.UserSearchToken{UserID: user.ID, Field: field, Token: token})
+		}
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	return db.Create(&tokens).Error
+}
+
+// prefixes are those at least MinPrefix characters long, the whole value
+// included.
+func prefixes(value string) []string {
+	var out []string
+	for i := range value {
+		if utf8.RuneCountInString(value[:i]) >= ireposity.MinPrefix {
+			out = append(out, value[:i])
+		}
+	}
+	if utf8.RuneCountInString(value) >= ireposity.MinPrefix {
+		out = append(out, value)
+	}
+	return out
+}
+
+func prefixToken(field, prefix string) (string, error) {
+	return database.BlindIndex(field + ":" + prefix)
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"template-grpc/internal/domain/entity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	"template-grpc/internal/infra/database"
+)
+
+func (u *userCrud) Upsert(ctx context.Context, users []entity.User) []*objectvalue.Response {
+	results := make([]*objectvalue.Response, len(users))
+	err := u.transaction(ctx, func(ctx context.Context, tx database.Resolver) error {
+		for i, user := range users {
+			results[i] = u.upsert(ctx, user)
+			// The savepoint rolled back, but the batch can run again whole.
+			if err := results[i].Err(); database.Retryable(err) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for i := range results {
+			results[i] = failed(err)
+		}
+	}
+	return results
+}
+
+// upsert runs in a savepoint of the batch's transaction, carried by ctx.
+func (u *userCrud) upsert(ctx context.Context, user entity.User) *objectvalue.Response {
+	var res *objectvalue.Response
+	err := u.transaction(ctx, func(ctx context.Context, tx database.Resolver) error {
+		hash, err := database.BlindIndex(user.Document)
+		if err != nil {
+			return err
+		}
+		var current entity.User
+		find := tx.Writer(ctx).
+			Where("document_hash = ?", hash).
+			Limit(1).
+			Find(&current)
+		if find.Error != nil {
+			return find.Error
+		}
+		if find.RowsAffected == 0 {
+			res = u.Insert(ctx, user)
+		} else {
+			user.ID, user.Version = current.ID, current.Version
+			res = u.Update(ctx, user)
+		}
+		return res.Err()
+	})
+	if err != nil {
+		return failed(err)
+	}
+	return res
+}
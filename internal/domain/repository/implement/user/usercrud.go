@@ -1,24 +1,203 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
 	objectvalue "template-grpc/internal/domain/object-value"
+	"template-grpc/internal/domain/repository/implement/audit"
 	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
+const table = "users"
+
 type userCrud struct {
+	db database.Resolver
+}
+
+func NewRepository(db database.Resolver) ireposity.IUserCrud {
+	return &userCrud{db: db}
+}
+
+func (u *userCrud) Insert(ctx context.Context, user entity.User) *objectvalue.Response {
+	now := time.Now().UTC()
+	actor := identity.Actor(ctx)
+	user.ID = 0
+	if err := Index(&user); err != nil {
+		return failed(err)
+	}
+	user.Version = 1
+	user.CreatedAt, user.UpdatedAt = now, now
+	user.CreatedBy, user.UpdatedBy = actor, actor
+	user.DeletedAt = gorm.DeletedAt{}
+
+	err := u.transaction(ctx, func(ctx context.Context, tx database.Resolver) error {
+		// A retry must not reuse the id of the attempt rolled back.
+		user.ID = 0
+		if err := tx.Writer(ctx).Create(&user).Error; err != nil {
+			return err
+		}
+		if err := SaveSearchTokens(tx.Writer(ctx), user); err != nil {
+			return err
+		}
+		if err := record(ctx, tx, user.ID, entity.ActionInsert, nil, user.AuditFields()); err != nil {
+			return err
+		}
+		return announce(ctx, tx, entity.EventUserCreated, user)
+	})
+	if err != nil {
+		return failed(err)
+	}
+	return objectvalue.Saved(user.ID, user.Version, "Usuario creado")
 }
 
-func NewRepository() ireposity.IUserCrud {
-	return &userCrud{}
+func (u *userCrud) Delete(ctx context.Context, id uint64) *objectvalue.Response {
+	err := u.transaction(ctx, func(ctx context.Context, tx database.Resolver) error {
+		db := tx.Writer(ctx)
+		var before entity.User
+		if err := db.First(&before, id).Error; err != nil {
+			return err
+		}
+		if err := db.Delete(&before).Error; err != nil {
+			return err
+		}
+		if err := record(ctx, tx, before.ID, entity.ActionDelete, before.AuditFields(), nil); err != nil {
+			return err
+		}
+		return announce(ctx, tx, entity.EventUserDeleted, before)
+	})
+	if err != nil {
+		return failed(err)
+	}
+	return objectvalue.Ok(id, "Usuario eliminado")
 }
 
-func (u *userCrud) Insert(entity.User) *objectvalue.Response {
-	return nil
+func (u *userCrud) Update(ctx context.Context, user entity.User) *objectvalue.Response {
+	if user.ID == 0 {
+		return objectvalue.Fail(codes.InvalidArgument, ireposity.MessageIDRequired)
+	}
+	if user.Version == 0 {
+		return objectvalue.Fail(codes.InvalidArgument, ireposity.MessageVersionRequired)
+	}
+
+	err := u.transaction(ctx, func(ctx context.Context, tx database.Resolver) error {
+		db := tx.Writer(ctx)
+		var before entity.User
+		if err := db.First(&before, user.ID).Error; err != nil {
+			return err
+		}
+		if before.Version != user.Version {
+			return objectvalue.Fail(codes.Aborted, ireposity.MessageVersionMismatch).Err()
+		}
+
+		// A struct update, unlike a map, goes through the column serializers.
+		changed := entity.User{
+			ID:        user.ID,
+			Name:      user.Name,
+			Document:  user.Document,
+			Phone:     user.Phone,
+			Version:   user.Version + 1,
+			UpdatedAt: time.Now().UTC(),
+			UpdatedBy: identity.Actor(ctx),
+		}
+		if err := Index(&changed); err != nil {
+			return err
+		}
+		result := db.Model(&changed).
+			Where("version = ?", user.Version).
+			Select("name", "name_search", "document", "document_hash", "phone", "phone_hash", "version", "updated_at", "updated_by").
+			Updates(&changed)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Someone else updated the user since it was read above.
+			return objectvalue.Fail(codes.Aborted, ireposity.MessageVersionMismatch).Err()
+		}
+		if err := SaveSearchTokens(db, changed); err != nil {
+			return err
+		}
+		if err := record(ctx, tx, user.ID, entity.ActionUpdate, before.AuditFields(), user.AuditFields()); err != nil {
+			return err
+		}
+		return announce(ctx, tx, entity.EventUserUpdated, changed)
+	})
+	if err != nil {
+		return failed(err)
+	}
+	return objectvalue.Saved(user.ID, user.Version+1, "Usuario actualizado")
 }
-func (u *userCrud) Delete(id int32) *objectvalue.Response {
-	return nil
+
+func (u *userCrud) Get(ctx context.Context, id uint64) (entity.User, *objectvalue.Response) {
+	var user entity.User
+	if err := u.db.Reader(ctx).First(&user, id).Error; err != nil {
+		return user, failed(err)
+	}
+	return user, objectvalue.Saved(user.ID, user.Version, "")
 }
-func (u *userCrud) Update(entity.User) *objectvalue.Response {
-	return nil
+
+func (u *userCrud) GetMany(ctx context.Context, ids []uint64) ([]entity.User, *objectvalue.Response) {
+	if len(ids) > ireposity.MaxBatchGet {
+		return nil, objectvalue.Fail(codes.InvalidArgument, ireposity.MessageTooManyIDs)
+	}
+	users := []entity.User{}
+	if len(ids) == 0 {
+		return users, objectvalue.Ok(0, "")
+	}
+	if err := u.db.Reader(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, failed(err)
+	}
+	return users, objectvalue.Ok(0, "")
+}
+
+// transaction runs a write, its audit event and its outbox event atomically, joining the
+// caller's transaction when there is one. On its own it is retried after a
+// serialization failure or deadlock, so fn must be safe to run again.
+func (u *userCrud) transaction(ctx context.Context, fn func(ctx context.Context, tx database.Resolver) error) error {
+	return database.NewTransactor(u.db, database.DefaultRetries).Transaction(ctx, fn)
+}
+
+func record(ctx context.Context, tx database.Resolver, id uint64, action string, before, after map[string]interface{}) error {
+	event, err := entity.NewAuditEvent(table, id, action, identity.Actor(ctx), before, after)
+	if err != nil {
+		return err
+	}
+	return audit.NewRepository(tx).Record(ctx, event)
+}
+
+// announce queues a domain event in the outbox, for the relay to publish
+// once the transaction commits. It is dated by the database clock, which
+// the feed settles it against.
+func announce(ctx context.Context, tx database.Resolver, eventType string, user entity.User) error {
+	id := identity.FromContext(ctx)
+	event, err := entity.NewUserEvent(eventType, id.Tenant, identity.Actor(ctx), user)
+	if err != nil {
+		return err
+	}
+	if event.CreatedAt, err = database.Now(tx.Writer(ctx)); err != nil {
+		return err
+	}
+	return tx.Writer(ctx).Create(&event).Error
+}
+
+func failed(err error) *objectvalue.Response {
+	var failure *objectvalue.Failure
+	switch {
+	case errors.As(err, &failure):
+		return failure.Response
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return objectvalue.Fail(codes.NotFound, ireposity.MessageNotFound)
+	case duplicated(err):
+		return objectvalue.Fail(codes.AlreadyExists, ireposity.MessageDuplicateDocument).Because(err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return objectvalue.Fail(status.FromContextError(err).Code(), err.Error()).Because(err)
+	}
+	return objectvalue.Fail(codes.Internal, err.Error()).Because(err)
 }
@@ -0,0 +1,102 @@
+package repository_test
+
+import (
+	"context"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/repository/conformance"
+	"template-grpc/internal/domain/repository/implement/audit"
+	repository "template-grpc/internal/domain/repository/implement/user"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+	"template-grpc/internal/infra/database/dbtest"
+	"template-grpc/internal/infra/outbox"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestUserCrud(t *testing.T) {
+	conformance.UserCrud(t, func(t *testing.T) ireposity.IUserCrud {
+		return repository.NewRepository(dbtest.Cluster(t))
+	})
+}
+
+func TestAuditLog(t *testing.T) {
+	conformance.AuditLog(t, func(t *testing.T) (ireposity.IUserCrud, ireposity.IAuditLog) {
+		cluster := dbtest.Cluster(t)
+		return repository.NewRepository(cluster), audit.NewRepository(cluster)
+	})
+}
+
+func TestTenancy(t *testing.T) {
+	conformance.Tenancy(t, func(t *testing.T) (ireposity.IUserCrud, ireposity.IAuditLog) {
+		cluster := dbtest.Cluster(t)
+		return repository.NewRepository(cluster), audit.NewRepository(cluster)
+	})
+}
+
+func TestUserFeed(t *testing.T) {
+	conformance.UserFeed(t, func(t *testing.T) (ireposity.IUserCrud, ireposity.IUserFeed) {
+		cluster := dbtest.Cluster(t)
+		return repository.NewRepository(cluster), repository.NewFeed(cluster, 50*time.Millisecond)
+	})
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	conformance.IdempotencyStore(t, func(t *testing.T) ireposity.IIdempotencyStore {
+		return repository.NewIdempotencyStore(dbtest.Cluster(t))
+	})
+}
+
+func TestNoIndexKey(t *testing.T) {
+	repo := repository.NewRepository(dbtest.Cluster(t))
+	database.SetKeyring(nil)
+
+	res := repo.Insert(context.Background(), entity.User{Name: "Ada", Document: "1001", Phone: "555-1001"})
+	if res.IsOk || codes.Code(res.Status) != codes.Internal {
+		t.Fatalf("insert without an index key: got %s (%q), want %s", codes.Code(res.Status), res.Message, codes.Internal)
+	}
+	if _, err := database.BlindIndex("1001"); err != database.ErrNoIndexKey {
+		t.Fatalf("BlindIndex without a keyring: got %v, want ErrNoIndexKey", err)
+	}
+}
+
+func TestFeedExpiresPrunedTokens(t *testing.T) {
+	db := dbtest.Open(t)
+	cluster := database.NewCluster(db, nil, database.Random)
+	users, feed := repository.NewRepository(cluster), repository.NewFeed(cluster, time.Millisecond)
+	for _, document := range []string{"1001", "1002", "1003"} {
+		if res := users.Insert(context.Background(), entity.User{Name: "Ada", Document: document}); !res.IsOk {
+			t.Fatal(res.Message)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	changes, res := feed.Changes(context.Background(), 0, 10)
+	if !res.IsOk || len(changes) != 3 {
+		t.Fatalf("Changes: got %d (%q), want 3", len(changes), res.Message)
+	}
+
+	err := db.Model(&entity.OutboxEvent{}).Where("1 = 1").Updates(map[string]interface{}{
+		"status":     entity.OutboxPublished,
+		"created_at": time.Now().UTC().Add(-time.Hour),
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.Prune(context.Background(), db, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the newest change is kept: the token before it is still good,
+	// the one before that lost a change.
+	if got, res := feed.Changes(context.Background(), changes[1].Token, 10); !res.IsOk || len(got) != 1 {
+		t.Fatalf("after the second token: got %d (%q), want 1", len(got), res.Message)
+	}
+	if _, res := feed.Changes(context.Background(), changes[0].Token, 10); codes.Code(res.Status) != codes.OutOfRange {
+		t.Fatalf("after the first token: got %s, want %s", codes.Code(res.Status), codes.OutOfRange)
+	}
+	if head, res := feed.Head(context.Background()); !res.IsOk || head != changes[2].Token {
+		t.Fatalf("Head: got %d (%q), want %d", head, res.Message, changes[2].Token)
+	}
+}
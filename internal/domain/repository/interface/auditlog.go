@@ -0,0 +1,27 @@
+package ireposity
+
+import (
+	"context"
+	"template-grpc/internal/domain/entity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	"time"
+)
+
+// AuditFilter narrows ListAuditEvents; zero fields match everything and the
+// time range includes From and excludes To.
+type AuditFilter struct {
+	Entity   string
+	EntityID uint64
+	Actor    string
+	From     time.Time
+	To       time.Time
+	Offset   int
+	Limit    int
+}
+
+// IAuditLog keeps who changed what. Repositories record events in the same
+// transaction as the change they describe.
+type IAuditLog interface {
+	Record(ctx context.Context, event entity.AuditEvent) error
+	List(ctx context.Context, filter AuditFilter) ([]entity.AuditEvent, *objectvalue.Response)
+}
@@ -0,0 +1,63 @@
+package ireposity
+
+import (
+	"context"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/filter"
+	objectvalue "template-grpc/internal/domain/object-value"
+)
+
+// MessageTokenExpired answers a resume token older than the changes kept.
+const MessageTokenExpired = "Los cambios posteriores al resume_token ya no se conservan, vuelva a empezar sin él"
+
+// UserChangeFields are the fields a watch filter may use; changes carry the
+// user but not who created it or when.
+var UserChangeFields = filter.Fields{
+	"id":         UserFields["id"],
+	"name":       UserFields["name"],
+	"document":   UserFields["document"],
+	"phone":      UserFields["phone"],
+	"version":    UserFields["version"],
+	"updated_at": UserFields["updated_at"],
+	"updated_by": UserFields["updated_by"],
+}
+
+// UserChange is a user event as watchers see it. Tokens grow with every
+// change of a tenant, so a watch resumes right after the last one it saw.
+type UserChange struct {
+	Token uint64
+	Type  string
+	User  entity.UserEvent
+}
+
+// Value returns a field of UserChangeFields the way List compares it.
+func (c UserChange) Value(field string) interface{} {
+	switch field {
+	case "id":
+		return c.User.ID
+	case "name":
+		return entity.NormalizeName(c.User.Name)
+	case "document":
+		return c.User.Document
+	case "phone":
+		return c.User.Phone
+	case "version":
+		return c.User.Version
+	case "updated_at":
+		return c.User.OccurredAt
+	case "updated_by":
+		return c.User.Actor
+	}
+	return nil
+}
+
+// IUserFeed reads the changes made to the caller's tenant users, fed by
+// the repository's writes.
+type IUserFeed interface {
+	// Changes returns up to limit changes after token, oldest first, or
+	// fails with codes.OutOfRange once some of them were pruned.
+	Changes(ctx context.Context, after uint64, limit int) ([]UserChange, *objectvalue.Response)
+	// Head returns the token of the latest change, where a watch that
+	// doesn't resume starts.
+	Head(ctx context.Context) (uint64, *objectvalue.Response)
+}
@@ -0,0 +1,64 @@
+package ireposity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"template-grpc/internal/domain/entity"
+	objectvalue "template-grpc/internal/domain/object-value"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	MessageIdempotencyKeyReused  = "La clave de idempotencia ya se usó con otra solicitud"
+	MessageIdempotencyInProgress = "Hay una solicitud en curso con la misma clave de idempotencia"
+	MessageIdempotencyLeaseLost  = "La clave de idempotencia venció antes de terminar la solicitud"
+)
+
+// IIdempotencyStore remembers the outcome of the writes made under an
+// idempotency key, per tenant, so a retried request gets the first answer
+// instead of writing again.
+type IIdempotencyStore interface {
+	// Begin claims key for the request hashed to hash, for lease at most,
+	// and returns the token Finish and Abandon take to prove the claim.
+	// When a request with the same hash already finished under key it
+	// returns that request's response to replay; a different hash fails
+	// with codes.FailedPrecondition and a request still running with
+	// codes.Aborted. Expired keys of the tenant are cleared first.
+	Begin(ctx context.Context, key, hash string, lease time.Duration) (token string, replay, res *objectvalue.Response)
+	// Finish stores the response of the request holding token, failed or
+	// not, and keeps it for ttl. It fails with codes.Aborted when the
+	// lease ran out and another request claimed key since.
+	Finish(ctx context.Context, key, token string, response *objectvalue.Response, ttl time.Duration) *objectvalue.Response
+	// Abandon frees key when its request was cut off before it wrote, so
+	// a retry runs again. A key claimed by another token is left alone.
+	Abandon(ctx context.Context, key, token string) *objectvalue.Response
+}
+
+// NewLeaseToken returns a random token for a claim made by Begin.
+func NewLeaseToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// Replay answers a request hashed to hash that found key already claimed,
+// the way Begin does.
+func Replay(key entity.IdempotencyKey, hash string) (replay, res *objectvalue.Response) {
+	if key.Hash != hash {
+		return nil, objectvalue.Fail(codes.FailedPrecondition, MessageIdempotencyKeyReused)
+	}
+	if key.Response == "" {
+		return nil, objectvalue.Fail(codes.Aborted, MessageIdempotencyInProgress)
+	}
+	replay = &objectvalue.Response{}
+	if err := json.Unmarshal([]byte(key.Response), replay); err != nil {
+		return nil, objectvalue.Fail(codes.Internal, err.Error())
+	}
+	return replay, objectvalue.Ok(0, "")
+}
@@ -0,0 +1,52 @@
+package ireposity
+
+import (
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/filter"
+)
+
+// UserFields are the user fields a List filter or order may use. Names
+// compare ignoring case and accents. Documents and phones are encrypted, so
+// they only compare by equality or by a prefix of at least MinPrefix
+// characters, and can't be sorted.
+var UserFields = filter.Fields{
+	"id":         {Kind: filter.Number, Sortable: true},
+	"name":       {Kind: filter.String, Sortable: true, Fold: entity.NormalizeName},
+	"document":   {Kind: filter.String, Ops: []filter.Op{filter.Eq, filter.Ne}, MinPrefix: MinPrefix},
+	"phone":      {Kind: filter.String, Ops: []filter.Op{filter.Eq, filter.Ne}, MinPrefix: MinPrefix},
+	"version":    {Kind: filter.Number, Sortable: true},
+	"created_at": {Kind: filter.Time, Sortable: true},
+	"updated_at": {Kind: filter.Time, Sortable: true},
+	"created_by": {Kind: filter.String, Sortable: true},
+	"updated_by": {Kind: filter.String, Sortable: true},
+}
+
+// ListQuery pages through the users matching Filter, an AIP-160 expression
+// such as `name:"Ana*" AND phone != ""`, sorted by OrderBy, such as
+// "name desc,id". Users are sorted by id when no order is given, and by id
+// after the given order otherwise, so pages stay stable.
+type ListQuery struct {
+	Filter  string
+	OrderBy string
+	Offset  int
+	Limit   int
+}
+
+// Parse returns the filter and order of q, or the message of the first
+// error in them.
+func (q ListQuery) Parse() (filter.Expr, []filter.Order, string) {
+	expr, err := filter.Parse(q.Filter, UserFields)
+	if err != nil {
+		return nil, nil, "filter: " + err.Error()
+	}
+	orders, err := filter.ParseOrder(q.OrderBy, UserFields)
+	if err != nil {
+		return nil, nil, "order_by: " + err.Error()
+	}
+	for _, order := range orders {
+		if order.Field == "id" {
+			return expr, orders, ""
+		}
+	}
+	return expr, append(orders, filter.Order{Field: "id"}), ""
+}
@@ -0,0 +1,74 @@
+package ireposity
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MinPrefix is the shortest document or phone prefix a search accepts.
+const MinPrefix = 3
+
+type Match int
+
+const (
+	// MatchDefault is contains for names and exact for documents and phones.
+	MatchDefault Match = iota
+	MatchExact
+	MatchPrefix
+	MatchContains
+)
+
+const (
+	MessageSearchEmpty    = "Indique al menos un criterio de búsqueda"
+	MessageContainsName   = "Solo el nombre admite búsqueda por contenido"
+	MessagePrefixTooShort = "El prefijo debe tener al menos 3 caracteres"
+	MessageUnknownMatch   = "Tipo de coincidencia desconocido"
+)
+
+// UserQuery finds users matching every non empty criterion. Names match
+// ignoring case and accents; documents and phones are encrypted, so they
+// only match exactly or by a prefix of at least MinPrefix characters.
+type UserQuery struct {
+	Name          string
+	NameMatch     Match
+	Document      string
+	DocumentMatch Match
+	Phone         string
+	PhoneMatch    Match
+	Offset        int
+	Limit         int
+}
+
+// Normalize trims the criteria, resolves default matches and returns the
+// message of the first invalid criterion, if any.
+func (q *UserQuery) Normalize() string {
+	q.Name = strings.TrimSpace(q.Name)
+	q.Document = strings.TrimSpace(q.Document)
+	q.Phone = strings.TrimSpace(q.Phone)
+	if q.Name == "" && q.Document == "" && q.Phone == "" {
+		return MessageSearchEmpty
+	}
+	if q.NameMatch == MatchDefault {
+		q.NameMatch = MatchContains
+	}
+	if q.DocumentMatch == MatchDefault {
+		q.DocumentMatch = MatchExact
+	}
+	if q.PhoneMatch == MatchDefault {
+		q.PhoneMatch = MatchExact
+	}
+
+	for _, match := range []Match{q.NameMatch, q.DocumentMatch, q.PhoneMatch} {
+		if match < MatchDefault || match > MatchContains {
+			return MessageUnknownMatch
+		}
+	}
+	if q.DocumentMatch == MatchContains || q.PhoneMatch == MatchContains {
+		return MessageContainsName
+	}
+	if q.DocumentMatch == MatchPrefix && q.Document != "" && utf8.RuneCountInString(q.Document) < MinPrefix ||
+		q.PhoneMatch == MatchPrefix && q.Phone != "" && utf8.RuneCountInString(q.Phone) < MinPrefix {
+		return MessagePrefixTooShort
+	}
+	return ""
+}
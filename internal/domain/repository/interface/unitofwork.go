@@ -0,0 +1,15 @@
+package ireposity
+
+import "context"
+
+// IRepositories hands out repositories that share one transaction.
+type IRepositories interface {
+	Users() IUserCrud
+}
+
+// IUnitOfWork changes several repositories atomically. Do commits when fn
+// returns nil and rolls back otherwise; calling Do again with the context
+// given to fn nests the work in a savepoint.
+type IUnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos IRepositories) error) error
+}
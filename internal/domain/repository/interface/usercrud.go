@@ -1,12 +1,66 @@
 package ireposity
 
 import (
+	"context"
 	"template-grpc/internal/domain/entity"
 	objectvalue "template-grpc/internal/domain/object-value"
 )
 
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+	// MaxBatchGet is how many ids GetMany takes at once.
+	MaxBatchGet = 1000
+)
+
+const (
+	MessageNotFound          = "Usuario no encontrado"
+	MessageDuplicateDocument = "El documento ya está registrado"
+	MessageIDRequired        = "El id es requerido"
+	MessageVersionRequired   = "La versión es requerida"
+	MessageVersionMismatch   = "El usuario fue modificado por otra persona"
+	MessageTooManyIDs        = "Se admiten hasta 1000 ids por consulta"
+)
+
+// IUserCrud stores users. Deleted users are kept but hidden from Get and
+// List, and their document stays reserved. Every user starts at version 1;
+// Update must be given the current version and bumps it, failing with
+// codes.Aborted when someone else changed the user first.
 type IUserCrud interface {
-	Insert(entity.User) *objectvalue.Response
-	Delete(id int32) *objectvalue.Response
-	Update(entity.User) *objectvalue.Response
+	Insert(ctx context.Context, user entity.User) *objectvalue.Response
+	Delete(ctx context.Context, id uint64) *objectvalue.Response
+	Update(ctx context.Context, user entity.User) *objectvalue.Response
+	// Upsert inserts each user or, when the tenant has an active user with
+	// its document, updates that one whatever its version. The batch is
+	// written in one transaction, each user in a savepoint of its own so a
+	// failed user leaves the others alone; the response at i is user i's,
+	// at version 1 when it was created.
+	Upsert(ctx context.Context, users []entity.User) []*objectvalue.Response
+	Get(ctx context.Context, id uint64) (entity.User, *objectvalue.Response)
+	// GetMany reads the active users among ids in a single query, each once
+	// and in no particular order; ids not found are left out. More than
+	// MaxBatchGet ids fail with codes.InvalidArgument.
+	GetMany(ctx context.Context, ids []uint64) ([]entity.User, *objectvalue.Response)
+	// List pages through the users matching query, failing with
+	// codes.InvalidArgument when its filter or order is invalid.
+	List(ctx context.Context, query ListQuery) ([]entity.User, *objectvalue.Response)
+	// Stream calls fn with every user matching query, in its order and
+	// ignoring Offset and Limit. Users are read through a cursor, so memory
+	// stays flat whatever the table size, and the first error fn returns
+	// stops it.
+	Stream(ctx context.Context, query ListQuery, fn func(user entity.User) error) *objectvalue.Response
+	// Search pages through the users matching query in id order, failing
+	// with codes.InvalidArgument when the query is invalid.
+	Search(ctx context.Context, query UserQuery) ([]entity.User, *objectvalue.Response)
+}
+
+// PageSize clamps a List limit, using DefaultLimit when none is given.
+func PageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
 }
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Now reads the database clock, so instances whose own clocks drift still
+// agree on the times they write and compare.
+func Now(db *gorm.DB) (time.Time, error) {
+	var query string
+	switch db.Dialector.Name() {
+	case "postgres":
+		query = "SELECT EXTRACT(EPOCH FROM CLOCK_TIMESTAMP())::float8"
+	case "mysql":
+		query = "SELECT UNIX_TIMESTAMP(NOW(6))"
+	default:
+		query = "SELECT (julianday('now') - 2440587.5) * 86400.0"
+	}
+	var seconds float64
+	if err := db.Raw(query).Scan(&seconds).Error; err != nil {
+		return time.Time{}, err
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+}
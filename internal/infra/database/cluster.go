@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Resolver hands repositories the connection to use for a call: writes go to
+// the primary, reads may be served by a replica.
+type Resolver interface {
+	Writer(ctx context.Context) *gorm.DB
+	Reader(ctx context.Context) *gorm.DB
+}
+
+// Policy picks the replica serving a read.
+type Policy string
+
+const (
+	Random     Policy = "random"
+	RoundRobin Policy = "round_robin"
+)
+
+type primaryKey struct{}
+
+// WithPrimary forces reads made with ctx onto the primary, for callers that
+// must see their own writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// PrimaryForced tells whether ctx came from WithPrimary.
+func PrimaryForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+type replica struct {
+	db      *gorm.DB
+	healthy atomic.Bool
+}
+
+// Cluster routes reads across healthy replicas and falls back to the primary
+// when none is available.
+type Cluster struct {
+	primary  *gorm.DB
+	replicas []*replica
+	policy   Policy
+	next     atomic.Uint64
+
+	stop chan struct{}
+	once sync.Once
+}
+
+func NewCluster(primary *gorm.DB, replicas []*gorm.DB, policy Policy) *Cluster {
+	c := &Cluster{primary: primary, policy: policy, stop: make(chan struct{})}
+	for _, db := range replicas {
+		r := &replica{db: db}
+		r.healthy.Store(true)
+		c.replicas = append(c.replicas, r)
+	}
+	return c
+}
+
+// Writer returns the primary, or the transaction carried by ctx.
+func (c *Cluster) Writer(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return c.primary.WithContext(ctx)
+}
+
+func (c *Cluster) Reader(ctx context.Context) *gorm.DB {
+	if _, inTx := ctx.Value(txKey{}).(*gorm.DB); inTx || PrimaryForced(ctx) {
+		return c.Writer(ctx)
+	}
+
+	healthy := make([]*replica, 0, len(c.replicas))
+	for _, r := range c.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return c.Writer(ctx)
+	}
+
+	var i int
+	if c.policy == RoundRobin {
+		i = int((c.next.Add(1) - 1) % uint64(len(healthy)))
+	} else {
+		i = rand.Intn(len(healthy))
+	}
+	return healthy[i].db.WithContext(ctx)
+}
+
+// Healthy returns how many replicas currently pass their health check.
+func (c *Cluster) Healthy() int {
+	n := 0
+	for _, r := range c.replicas {
+		if r.healthy.Load() {
+			n++
+		}
+	}
+	return n
+}
+
+// Watch pings every replica each interval until Close, taking failing ones
+// out of rotation and putting them back once they answer again.
+func (c *Cluster) Watch(interval time.Duration) {
+	if len(c.replicas) == 0 || interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				c.check(interval)
+			}
+		}
+	}()
+}
+
+func (c *Cluster) check(timeout time.Duration) {
+	for _, r := range c.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		r.healthy.Store(ping(ctx, r.db) == nil)
+		cancel()
+	}
+}
+
+// Close stops the health checks and closes the connection pools of the
+// primary and the replicas, so it goes after everything using them. A nil
+// cluster, left by the memory driver, has nothing to close.
+func (c *Cluster) Close() error {
+	if c == nil {
+		return nil
+	}
+	var err error
+	c.once.Do(func() {
+		close(c.stop)
+		pools := []*gorm.DB{c.primary}
+		for _, r := range c.replicas {
+			pools = append(pools, r.db)
+		}
+		for _, db := range pools {
+			sqlDB, dbErr := db.DB()
+			if dbErr == nil {
+				dbErr = sqlDB.Close()
+			}
+			if err == nil {
+				err = dbErr
+			}
+		}
+	})
+	return err
+}
+
+func ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,62 @@
+// Package dbtest opens throwaway databases for tests.
+package dbtest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"template-grpc/internal/infra/database"
+	"template-grpc/internal/infra/migration"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var opened atomic.Int64
+
+// Open returns an in-memory sqlite database of its own, migrated and scoped
+// by the tenancy plugin, and sets a test keyring for as long as the test
+// runs.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	Keyring(t)
+	dsn := fmt.Sprintf("file:dbtest%d?mode=memory&cache=shared", opened.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{SkipDefaultTransaction: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.Use(database.Tenancy{}); err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migration.New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// Cluster opens a database like Open and serves it as a cluster without
+// replicas.
+func Cluster(t testing.TB) *database.Cluster {
+	return database.NewCluster(Open(t), nil, database.Random)
+}
+
+// Keyring sets a keyring with fixed keys until the test ends.
+func Keyring(t testing.TB) {
+	t.Helper()
+	k, err := database.NewKeyring("test", map[string][]byte{"test": make([]byte, 32)}, []byte("test index key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetKeyring(k)
+	t.Cleanup(func() { database.SetKeyring(nil) })
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm/schema"
+)
+
+// encryptedPrefix marks values written by a keyring, so columns holding
+// plain text from before encryption was enabled can still be read.
+const encryptedPrefix = "enc:"
+
+var (
+	ErrUnknownKey = errors.New("value encrypted with an unknown key")
+	// ErrNoIndexKey is returned by BlindIndex until a keyring is set: an
+	// unkeyed hash of a document is as good as the document.
+	ErrNoIndexKey = errors.New("the blind index key is not configured")
+
+	keyring atomic.Pointer[Keyring]
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// Keyring encrypts with AES-GCM under the current key and decrypts with any
+// key it holds. Values are stored as enc:<key id>:<base64 nonce+ciphertext>,
+// so a new current key can be introduced while old rows stay readable.
+type Keyring struct {
+	current  string
+	aeads    map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// NewKeyring takes AES keys of 16, 24 or 32 bytes by id; current names the
+// key used for writes. indexKey signs the blind indexes. Without a current
+// key values are written in plain text and the keys are only read with.
+func NewKeyring(current string, keys map[string][]byte, indexKey []byte) (*Keyring, error) {
+	if _, ok := keys[current]; !ok && current != "" {
+		return nil, fmt.Errorf("current key %q is not in the keyring", current)
+	}
+	if len(indexKey) == 0 {
+		return nil, errors.New("the blind index needs a key")
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		if strings.Contains(id, ":") {
+			return nil, fmt.Errorf("key id %q has a colon", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		aeads[id] = aead
+	}
+	return &Keyring{current: current, aeads: aeads, indexKey: indexKey}, nil
+}
+
+// SetKeyring makes the keyring used by the encrypted serializer and
+// BlindIndex; nil stores values in plain text and leaves BlindIndex
+// failing.
+func SetKeyring(k *Keyring) {
+	keyring.Store(k)
+}
+
+// Encrypt seals plain under the current key. The column name is bound as
+// additional data, so a value copied into another column won't decrypt.
+func (k *Keyring) Encrypt(plain, column string) (string, error) {
+	if k.current == "" {
+		return plain, nil
+	}
+	aead := k.aeads[k.current]
+	sealed := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(sealed); err != nil {
+		return "", err
+	}
+	sealed = aead.Seal(sealed, sealed, []byte(plain), []byte(column))
+	return encryptedPrefix + k.current + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value written by Encrypt and returns any other value as is.
+func (k *Keyring) Decrypt(value, column string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	id, encoded, found := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !found {
+		return "", fmt.Errorf("malformed encrypted value in %s", column)
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("%w %q in %s", ErrUnknownKey, id, column)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value in %s", column)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(column))
+	if err != nil {
+		return "", fmt.Errorf("decrypting %s: %w", column, err)
+	}
+	return string(plain), nil
+}
+
+// Seal encrypts value with the keyring set by SetKeyring, bound to purpose
+// as Encrypt binds a column, and returns it as is without a keyring.
+func Seal(value, purpose string) (string, error) {
+	if k := keyring.Load(); k != nil {
+		return k.Encrypt(value, purpose)
+	}
+	return value, nil
+}
+
+// Unseal opens a value written by Seal for the same purpose.
+func Unseal(value, purpose string) (string, error) {
+	if k := keyring.Load(); k != nil {
+		return k.Decrypt(value, purpose)
+	}
+	if strings.HasPrefix(value, encryptedPrefix) {
+		return "", fmt.Errorf("%s is encrypted but no keys are configured", purpose)
+	}
+	return value, nil
+}
+
+// BlindIndex is a keyed hash of value for exact-match lookups on an
+// encrypted column. It fails with ErrNoIndexKey until a keyring is set.
+func BlindIndex(value string) (string, error) {
+	k := keyring.Load()
+	if k == nil {
+		return "", ErrNoIndexKey
+	}
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// EncryptedSerializer encrypts string fields tagged serializer:encrypted
+// with the keyring set by SetKeyring.
+type EncryptedSerializer struct{}
+
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported value %T in %s", dbValue, field.DBName)
+	}
+
+	plain, err := Unseal(value, field.DBName)
+	if err != nil {
+		return err
+	}
+	return field.Set(ctx, dst, plain)
+}
+
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string to be encrypted", field.DBName)
+	}
+	return Seal(value, field.DBName)
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"template-grpc/internal/domain/identity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const tenantColumn = "tenant_id"
+
+var ErrTenantMismatch = errors.New("row belongs to another tenant")
+
+// Tenancy is a GORM plugin scoping every model with a tenant_id column to the
+// tenant in the statement's context: reads, updates and deletes only match
+// that tenant's rows and inserts are stamped with it. Raw SQL is not scoped.
+type Tenancy struct{}
+
+func (Tenancy) Name() string {
+	return "tenancy"
+}
+
+func (Tenancy) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenancy:create", stampTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenancy:query", scopeTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenancy:update", scopeTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenancy:delete", scopeTenant); err != nil {
+		return err
+	}
+	return callbacks.Row().Before("gorm:row").Register("tenancy:row", scopeTenant)
+}
+
+func scopeTenant(db *gorm.DB) {
+	if db.Error != nil || !hasTenant(db) {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: tenantColumn},
+			Value:  identity.FromContext(db.Statement.Context).Tenant,
+		},
+	}})
+}
+
+func stampTenant(db *gorm.DB) {
+	if db.Error != nil || !hasTenant(db) {
+		return
+	}
+	ctx := db.Statement.Context
+	field := db.Statement.Schema.LookUpField(tenantColumn)
+	tenant := identity.FromContext(ctx).Tenant
+
+	stamp := func(row reflect.Value) {
+		current, zero := field.ValueOf(ctx, row)
+		if !zero && current != tenant {
+			db.AddError(ErrTenantMismatch)
+			return
+		}
+		if err := field.Set(ctx, row, tenant); err != nil {
+			db.AddError(err)
+		}
+	}
+
+	rows := db.Statement.ReflectValue
+	switch rows.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rows.Len(); i++ {
+			stamp(reflect.Indirect(rows.Index(i)))
+		}
+	case reflect.Struct:
+		stamp(rows)
+	}
+}
+
+func hasTenant(db *gorm.DB) bool {
+	return db.Statement.Schema != nil && db.Statement.Schema.LookUpField(tenantColumn) != nil
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// DefaultRetries is how many times a transaction is run again after a
+// serialization failure or deadlock.
+const DefaultRetries = 3
+
+type (
+	txKey    struct{}
+	hooksKey struct{}
+)
+
+// Transactor runs functions inside a database transaction. A call made with
+// the context of an outer transaction opens a savepoint instead, and only the
+// outermost transaction is retried when the database reports a serialization
+// failure or a deadlock. Reads made with the context given to fn are forced
+// onto the primary, skipping replicas and caches.
+type Transactor struct {
+	resolver   Resolver
+	maxRetries int
+}
+
+func NewTransactor(resolver Resolver, maxRetries int) *Transactor {
+	return &Transactor{resolver: resolver, maxRetries: maxRetries}
+}
+
+// Transaction commits when fn returns nil and rolls back otherwise. fn gets a
+// context carrying the transaction and a Resolver bound to it.
+func (t *Transactor) Transaction(ctx context.Context, fn func(ctx context.Context, tx Resolver) error) error {
+	if outer, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return outer.Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txKey{}, tx), txResolver{tx})
+		})
+	}
+
+	backoff := 10 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		var hooks []func()
+		err := t.resolver.Writer(ctx).Transaction(func(tx *gorm.DB) error {
+			ctx := context.WithValue(WithPrimary(ctx), hooksKey{}, &hooks)
+			return fn(context.WithValue(ctx, txKey{}, tx), txResolver{tx})
+		})
+		if err == nil {
+			for _, hook := range hooks {
+				hook()
+			}
+			return nil
+		}
+		if attempt >= t.maxRetries || !Retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff + time.Duration(rand.Int63n(int64(backoff)))):
+		}
+		backoff *= 2
+	}
+}
+
+// AfterCommit runs hook once the transaction carried by ctx commits, or
+// right away outside one. Hooks of a savepoint that rolled back still run
+// if the transaction commits, so they must be harmless then, as
+// invalidating a cache is.
+func AfterCommit(ctx context.Context, hook func()) {
+	if hooks, ok := ctx.Value(hooksKey{}).(*[]func()); ok {
+		*hooks = append(*hooks, hook)
+		return
+	}
+	hook()
+}
+
+// txResolver sends reads and writes alike through the transaction.
+type txResolver struct {
+	tx *gorm.DB
+}
+
+func (r txResolver) Writer(ctx context.Context) *gorm.DB {
+	return r.tx.WithContext(ctx)
+}
+
+func (r txResolver) Reader(ctx context.Context) *gorm.DB {
+	return r.tx.WithContext(ctx)
+}
+
+// Retryable reports serialization failures and deadlocks, after which the
+// whole transaction can safely run again.
+func Retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// serialization_failure, deadlock_detected
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// ER_LOCK_DEADLOCK, ER_LOCK_WAIT_TIMEOUT
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
@@ -0,0 +1,3 @@
+DROP INDEX idx_users_document ON users;
+DROP INDEX idx_users_deleted_at ON users;
+ALTER TABLE users DROP COLUMN deleted_at;
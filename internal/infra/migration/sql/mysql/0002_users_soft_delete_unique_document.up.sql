@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN deleted_at DATETIME(3) NULL;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX idx_users_document ON users (document);
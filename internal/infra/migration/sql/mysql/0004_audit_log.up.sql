@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN created_at DATETIME(3) NULL;
+ALTER TABLE users ADD COLUMN updated_at DATETIME(3) NULL;
+ALTER TABLE users ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN updated_by VARCHAR(255) NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    entity VARCHAR(64) NOT NULL,
+    entity_id BIGINT UNSIGNED NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    changes TEXT NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (id),
+    INDEX idx_audit_log_entity (entity, entity_id),
+    INDEX idx_audit_log_actor (actor),
+    INDEX idx_audit_log_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
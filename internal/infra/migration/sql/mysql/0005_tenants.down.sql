@@ -0,0 +1,5 @@
+DROP INDEX idx_audit_log_tenant ON audit_log;
+ALTER TABLE audit_log DROP COLUMN tenant_id;
+DROP INDEX idx_users_tenant_document ON users;
+CREATE UNIQUE INDEX idx_users_document ON users (document);
+ALTER TABLE users DROP COLUMN tenant_id;
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+DROP INDEX idx_users_document ON users;
+CREATE UNIQUE INDEX idx_users_tenant_document ON users (tenant_id, document);
+ALTER TABLE audit_log ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX idx_audit_log_tenant ON audit_log (tenant_id);
@@ -0,0 +1,3 @@
+DROP INDEX idx_users_tenant_document_hash ON users;
+CREATE UNIQUE INDEX idx_users_tenant_document ON users (tenant_id, document);
+ALTER TABLE users DROP COLUMN document_hash;
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN document_hash CHAR(64) NULL;
+DROP INDEX idx_users_tenant_document ON users;
+CREATE UNIQUE INDEX idx_users_tenant_document_hash ON users (tenant_id, document_hash);
+ALTER TABLE users MODIFY document VARCHAR(512) NOT NULL;
+ALTER TABLE users MODIFY phone VARCHAR(512) NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE audit_log ADD COLUMN reason VARCHAR(1024) NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    tenant VARCHAR(64) NOT NULL,
+    topic VARCHAR(255) NOT NULL,
+    type VARCHAR(255) NOT NULL,
+    event_key VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME(3) NOT NULL,
+    last_error TEXT NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    published_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    INDEX idx_outbox_pending (status, next_attempt_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_search_tokens;
+DROP INDEX idx_users_phone_hash ON users;
+DROP INDEX idx_users_name_search ON users;
+ALTER TABLE users DROP COLUMN phone_hash;
+ALTER TABLE users DROP COLUMN name_search;
@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN name_search VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN phone_hash CHAR(64) NULL;
+CREATE INDEX idx_users_name_search ON users (tenant_id, name_search);
+CREATE INDEX idx_users_phone_hash ON users (tenant_id, phone_hash);
+CREATE TABLE IF NOT EXISTS user_search_tokens (
+    user_id BIGINT UNSIGNED NOT NULL,
+    field VARCHAR(16) NOT NULL,
+    token CHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, field, token),
+    INDEX idx_user_search_tokens_token (field, token)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1 @@
+DROP INDEX idx_outbox_feed ON outbox;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    tenant_id VARCHAR(64) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    response TEXT NOT NULL,
+    expires_at DATETIME(3) NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (tenant_id, idempotency_key),
+    INDEX idx_idempotency_keys_expires_at (tenant_id, expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
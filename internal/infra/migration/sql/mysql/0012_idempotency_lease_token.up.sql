@@ -0,0 +1 @@
+ALTER TABLE idempotency_keys ADD COLUMN lease_token CHAR(32) NOT NULL DEFAULT '';
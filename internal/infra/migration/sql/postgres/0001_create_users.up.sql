@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    document TEXT NOT NULL,
+    phone TEXT NOT NULL
+);
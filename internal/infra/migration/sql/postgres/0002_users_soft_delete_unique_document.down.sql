@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_document;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+ALTER TABLE users DROP COLUMN deleted_at;
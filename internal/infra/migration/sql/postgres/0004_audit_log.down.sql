@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS audit_log;
+ALTER TABLE users DROP COLUMN updated_by;
+ALTER TABLE users DROP COLUMN created_by;
+ALTER TABLE users DROP COLUMN updated_at;
+ALTER TABLE users DROP COLUMN created_at;
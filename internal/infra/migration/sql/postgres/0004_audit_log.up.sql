@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN created_at TIMESTAMPTZ NULL;
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMPTZ NULL;
+ALTER TABLE users ADD COLUMN created_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN updated_by TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    entity TEXT NOT NULL,
+    entity_id BIGINT NOT NULL,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_audit_log_entity ON audit_log (entity, entity_id);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor);
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_tenant_document_hash;
+CREATE UNIQUE INDEX idx_users_tenant_document ON users (tenant_id, document);
+ALTER TABLE users DROP COLUMN document_hash;
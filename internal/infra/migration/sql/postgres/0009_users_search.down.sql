@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_search_tokens;
+DROP INDEX IF EXISTS idx_users_phone_hash;
+DROP INDEX IF EXISTS idx_users_name_search;
+ALTER TABLE users DROP COLUMN phone_hash;
+ALTER TABLE users DROP COLUMN name_search;
@@ -0,0 +1 @@
+CREATE INDEX idx_outbox_feed ON outbox (tenant, topic, id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_audit_log_tenant;
+ALTER TABLE audit_log DROP COLUMN tenant_id;
+DROP INDEX IF EXISTS idx_users_tenant_document;
+CREATE UNIQUE INDEX idx_users_document ON users (document);
+ALTER TABLE users DROP COLUMN tenant_id;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN document_hash CHAR(64) NULL;
+DROP INDEX IF EXISTS idx_users_tenant_document;
+CREATE UNIQUE INDEX idx_users_tenant_document_hash ON users (tenant_id, document_hash);
@@ -0,0 +1 @@
+ALTER TABLE audit_log ADD COLUMN reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN name_search TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN phone_hash CHAR(64) NULL;
+CREATE INDEX idx_users_name_search ON users (tenant_id, name_search);
+CREATE INDEX idx_users_phone_hash ON users (tenant_id, phone_hash);
+CREATE TABLE IF NOT EXISTS user_search_tokens (
+    user_id INTEGER NOT NULL,
+    field TEXT NOT NULL,
+    token CHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, field, token)
+);
+CREATE INDEX idx_user_search_tokens_token ON user_search_tokens (field, token);
@@ -0,0 +1,49 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type kafkaPublisher struct {
+	writer *kafka.Writer
+	prefix string
+}
+
+// NewKafkaPublisher publishes to the topic prefix+topic, keyed by the
+// message key so each user's events land in one partition, in order. It
+// waits for every in-sync replica to acknowledge. The relay publishes one
+// event at a time, so each write is sent at once instead of waiting for a
+// batch to fill.
+func NewKafkaPublisher(brokers []string, prefix string) Publisher {
+	return &kafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchSize:    1,
+			BatchTimeout: time.Millisecond,
+		},
+		prefix: prefix,
+	}
+}
+
+func (k *kafkaPublisher) Publish(ctx context.Context, msg Message) error {
+	return k.writer.WriteMessages(ctx, kafka.Message{
+		Topic: k.prefix + msg.Topic,
+		Key:   []byte(msg.Key),
+		Value: msg.Payload,
+		Headers: []kafka.Header{
+			{Key: "id", Value: []byte(msgID(msg))},
+			{Key: "type", Value: []byte(msg.Type)},
+			{Key: "tenant", Value: []byte(msg.Tenant)},
+		},
+		Time: msg.CreatedAt,
+	})
+}
+
+func (k *kafkaPublisher) Close() error {
+	return k.writer.Close()
+}
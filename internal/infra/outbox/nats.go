@@ -0,0 +1,44 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+)
+
+type natsPublisher struct {
+	conn   *nats.Conn
+	js     nats.JetStreamContext
+	prefix string
+}
+
+// NewNatsPublisher publishes to the JetStream subject prefix+topic+"."+type
+// and waits for the stream's acknowledgement. The message id doubles as the
+// JetStream deduplication id, so retries within the stream's window are
+// dropped by the server.
+func NewNatsPublisher(url, prefix string) (Publisher, error) {
+	conn, err := nats.Connect(url, nats.Name("usuario-outbox"))
+	if err != nil {
+		return nil, err
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &natsPublisher{conn: conn, js: js, prefix: prefix}, nil
+}
+
+func (n *natsPublisher) Publish(ctx context.Context, msg Message) error {
+	out := nats.NewMsg(n.prefix + msg.Topic + "." + msg.Type)
+	out.Data = msg.Payload
+	out.Header.Set(nats.MsgIdHdr, msgID(msg))
+	out.Header.Set("Tenant", msg.Tenant)
+	out.Header.Set("Key", msg.Key)
+	_, err := n.js.PublishMsg(out, nats.Context(ctx))
+	return err
+}
+
+func (n *natsPublisher) Close() error {
+	return n.conn.Drain()
+}
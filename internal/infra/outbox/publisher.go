@@ -0,0 +1,79 @@
+// Package outbox relays the events queued in the outbox table to a message
+// broker. Delivery is at least once: an event is marked published only
+// after the publisher accepted it, so consumers must tolerate duplicates.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is an outbox event as handed to a publisher.
+type Message struct {
+	ID        uint64          `json:"id"`
+	Topic     string          `json:"topic"`
+	Type      string          `json:"type"`
+	Key       string          `json:"key"`
+	Tenant    string          `json:"tenant"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Publisher delivers messages to a broker. Publish returns once the broker
+// has the message; an error makes the relay retry it later.
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+type logPublisher struct{}
+
+// NewLogPublisher writes every message to the standard logger, for
+// development. Payloads carry personal data, so only their size is logged.
+func NewLogPublisher() Publisher {
+	return logPublisher{}
+}
+
+func (logPublisher) Publish(_ context.Context, msg Message) error {
+	log.Printf("outbox: %s %s %s key=%s tenant=%q payload=%d bytes", msgID(msg), msg.Topic, msg.Type, msg.Key, msg.Tenant, len(msg.Payload))
+	return nil
+}
+
+func (logPublisher) Close() error {
+	return nil
+}
+
+type filePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFilePublisher appends messages to path as JSON lines.
+func NewFilePublisher(path string) (Publisher, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	return &filePublisher{file: file}, nil
+}
+
+func (f *filePublisher) Publish(_ context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.file.Sync()
+}
+
+func (f *filePublisher) Close() error {
+	return f.file.Close()
+}
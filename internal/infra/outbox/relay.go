@@ -0,0 +1,264 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/infra/database"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Options tune a relay; zero values take the defaults noted.
+type Options struct {
+	// Interval between polls when the outbox is drained, 1s.
+	Interval time.Duration
+	// BatchSize events are claimed per poll, 100.
+	BatchSize int
+	// MaxAttempts before an event is dead lettered, 10.
+	MaxAttempts int
+	// Retries back off from RetryInterval (1s) doubling up to
+	// MaxRetryInterval (5m).
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+	// PublishTimeout bounds each publish, 10s.
+	PublishTimeout time.Duration
+	// Lease keeps a claimed batch from other relays while it is published,
+	// 5m. A batch still publishing when it runs out may go out twice.
+	Lease time.Duration
+	// Retention keeps published events for the watchers resuming from
+	// them, 7 days; they are pruned every PruneInterval, 1h.
+	Retention     time.Duration
+	PruneInterval time.Duration
+}
+
+// Relay publishes pending outbox events in id order. Several relays may
+// share a database: each claims its batch by pushing next_attempt_at past
+// a lease, in a short transaction that on mysql and postgres skips rows
+// another relay is claiming, and publishes with no transaction open. A
+// user's events keep their order unless one is retried while a later one
+// goes through.
+type Relay struct {
+	db        *gorm.DB
+	publisher Publisher
+	opts      Options
+}
+
+func NewRelay(db *gorm.DB, publisher Publisher, opts Options) *Relay {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = time.Second
+	}
+	if opts.MaxRetryInterval <= 0 {
+		opts.MaxRetryInterval = 5 * time.Minute
+	}
+	if opts.PublishTimeout <= 0 {
+		opts.PublishTimeout = 10 * time.Second
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = 5 * time.Minute
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = 7 * 24 * time.Hour
+	}
+	if opts.PruneInterval <= 0 {
+		opts.PruneInterval = time.Hour
+	}
+	return &Relay{db: db, publisher: publisher, opts: opts}
+}
+
+// Run relays until ctx is done, polling again straight away while batches
+// come back full, and prunes the events published before the retention.
+func (r *Relay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	var pruned time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if time.Since(pruned) >= r.opts.PruneInterval {
+			pruned = time.Now()
+			if _, err := Prune(ctx, r.db, r.opts.Retention); err != nil && ctx.Err() == nil {
+				log.Printf("outbox: pruning: %v", err)
+			}
+		}
+		n, err := r.Flush(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("outbox: %v", err)
+		}
+		if n == r.opts.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(r.opts.Interval)
+		}
+	}
+}
+
+// Flush claims one batch of due events and publishes it, returning how many
+// events it claimed.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	events, err := r.claim(ctx)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	// Once a key fails, its later events wait so they don't overtake it.
+	failedKeys := map[string]bool{}
+	for i := range events {
+		event := &events[i]
+		if failedKeys[event.Key] {
+			event.NextAttemptAt = time.Now().UTC()
+			continue
+		}
+		if err := r.publish(ctx, *event); err != nil {
+			failedKeys[event.Key] = true
+			r.failed(event, err)
+		} else {
+			now := time.Now().UTC()
+			event.Status, event.PublishedAt, event.LastError = entity.OutboxPublished, &now, ""
+			event.Attempts++
+		}
+	}
+	return len(events), r.mark(events)
+}
+
+// claim leases a batch of due events to this relay.
+func (r *Relay) claim(ctx context.Context) ([]entity.OutboxEvent, error) {
+	var events []entity.OutboxEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		query := tx.Where("status = ? AND next_attempt_at <= ?", entity.OutboxPending, now).
+			Order("id").
+			Limit(r.opts.BatchSize)
+		if tx.Dialector.Name() != "sqlite" {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		if err := query.Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		ids := make([]uint64, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		return tx.Model(&entity.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(r.opts.Lease)).Error
+	})
+	return events, err
+}
+
+// mark records how publishing a claimed batch went. It outlives ctx: the
+// events went out, and forgetting it would only publish them again.
+func (r *Relay) mark(events []entity.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.opts.PublishTimeout)
+	defer cancel()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range events {
+			err := tx.Model(&events[i]).
+				Select("status", "attempts", "next_attempt_at", "last_error", "published_at").
+				Updates(&events[i]).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Relay) publish(ctx context.Context, event entity.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.PublishTimeout)
+	defer cancel()
+	return r.publisher.Publish(ctx, Message{
+		ID:        event.ID,
+		Topic:     event.Topic,
+		Type:      event.Type,
+		Key:       event.Key,
+		Tenant:    event.Tenant,
+		Payload:   json.RawMessage(event.Payload),
+		CreatedAt: event.CreatedAt,
+	})
+}
+
+// failed schedules the next attempt with exponential backoff, or dead
+// letters the event once it has used up its attempts.
+func (r *Relay) failed(event *entity.OutboxEvent, err error) {
+	event.Attempts++
+	event.LastError = err.Error()
+	if event.Attempts >= r.opts.MaxAttempts {
+		event.Status = entity.OutboxDead
+		log.Printf("outbox: event %d dead lettered after %d attempts: %v", event.ID, event.Attempts, err)
+		return
+	}
+	backoff := r.opts.RetryInterval
+	for i := 1; i < event.Attempts && backoff < r.opts.MaxRetryInterval; i++ {
+		backoff *= 2
+	}
+	if backoff > r.opts.MaxRetryInterval {
+		backoff = r.opts.MaxRetryInterval
+	}
+	event.NextAttemptAt = time.Now().UTC().Add(backoff)
+}
+
+// Prune deletes the events published more than retention ago, dated by the
+// database clock. Only the oldest ids go, up to the first event still
+// pending, dead or recent, and the newest event always stays, so the feed
+// can tell a resume token whose next events were pruned.
+func Prune(ctx context.Context, db *gorm.DB, retention time.Duration) (int64, error) {
+	db = db.WithContext(ctx)
+	now, err := database.Now(db)
+	if err != nil {
+		return 0, err
+	}
+	var bound uint64
+	err = db.Model(&entity.OutboxEvent{}).
+		Select("COALESCE(MIN(id), 0)").
+		Where("status <> ? OR created_at > ?", entity.OutboxPublished, now.Add(-retention)).
+		Scan(&bound).Error
+	if err != nil {
+		return 0, err
+	}
+	if bound == 0 {
+		err := db.Model(&entity.OutboxEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&bound).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	result := db.Where("id < ?", bound).Delete(&entity.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// Requeue gives dead lettered events a fresh set of attempts.
+func Requeue(ctx context.Context, db *gorm.DB) (int64, error) {
+	result := db.WithContext(ctx).Model(&entity.OutboxEvent{}).
+		Where("status = ?", entity.OutboxDead).
+		Updates(map[string]interface{}{
+			"status":          entity.OutboxPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now().UTC(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// msgID identifies a message to brokers that deduplicate.
+func msgID(msg Message) string {
+	return "outbox-" + strconv.FormatUint(msg.ID, 10)
+}
@@ -0,0 +1,138 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/infra/database/dbtest"
+	"template-grpc/internal/infra/outbox"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type publisherFunc func(ctx context.Context, msg outbox.Message) error
+
+func (f publisherFunc) Publish(ctx context.Context, msg outbox.Message) error { return f(ctx, msg) }
+func (publisherFunc) Close() error                                            { return nil }
+
+func queue(t *testing.T, db *gorm.DB, userID uint64) entity.OutboxEvent {
+	t.Helper()
+	event, err := entity.NewUserEvent(entity.EventUserCreated, "acme", "alice", entity.User{ID: userID, Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.NextAttemptAt = event.NextAttemptAt.Add(-time.Second)
+	if err := db.Create(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func load(t *testing.T, db *gorm.DB, id uint64) entity.OutboxEvent {
+	t.Helper()
+	var event entity.OutboxEvent
+	if err := db.First(&event, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestFlushPublishesAClaimedBatch(t *testing.T) {
+	db := dbtest.Open(t)
+	first := queue(t, db, 1)
+
+	var seen []outbox.Message
+	relay := outbox.NewRelay(db, publisherFunc(func(ctx context.Context, msg outbox.Message) error {
+		// The claim is committed before publishing, so other relays see
+		// the lease and nothing stays locked while the broker answers.
+		if claimed := load(t, db, msg.ID); !claimed.NextAttemptAt.After(time.Now().UTC()) {
+			t.Errorf("event %d is not leased while it is published", msg.ID)
+		}
+		seen = append(seen, msg)
+		return nil
+	}), outbox.Options{})
+
+	n, err := relay.Flush(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("Flush: got %d, %v", n, err)
+	}
+	if len(seen) != 1 || seen[0].ID != first.ID || seen[0].Type != entity.EventUserCreated {
+		t.Fatalf("published %+v", seen)
+	}
+	if event := load(t, db, first.ID); event.Status != entity.OutboxPublished || event.Attempts != 1 || event.PublishedAt == nil {
+		t.Fatalf("after publishing: %+v", event)
+	}
+
+	if n, err := relay.Flush(context.Background()); err != nil || n != 0 {
+		t.Fatalf("second Flush: got %d, %v", n, err)
+	}
+}
+
+func TestFlushRetriesAFailedKeyInOrder(t *testing.T) {
+	db := dbtest.Open(t)
+	first := queue(t, db, 1)
+	second := queue(t, db, 1)
+	other := queue(t, db, 2)
+
+	var published []uint64
+	relay := outbox.NewRelay(db, publisherFunc(func(ctx context.Context, msg outbox.Message) error {
+		if msg.ID == first.ID {
+			return errors.New("broker down")
+		}
+		published = append(published, msg.ID)
+		return nil
+	}), outbox.Options{RetryInterval: time.Minute})
+
+	if _, err := relay.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(published) != 1 || published[0] != other.ID {
+		t.Fatalf("published %v, want only %d", published, other.ID)
+	}
+
+	failed := load(t, db, first.ID)
+	if failed.Status != entity.OutboxPending || failed.Attempts != 1 || failed.LastError != "broker down" {
+		t.Fatalf("failed event: %+v", failed)
+	}
+	if !failed.NextAttemptAt.After(time.Now().UTC().Add(30 * time.Second)) {
+		t.Fatalf("failed event retries at %v, want about a minute from now", failed.NextAttemptAt)
+	}
+	// The event behind it was claimed but not tried, so it is released.
+	if waiting := load(t, db, second.ID); waiting.Attempts != 0 || waiting.NextAttemptAt.After(time.Now().UTC()) {
+		t.Fatalf("waiting event: %+v", waiting)
+	}
+}
+
+func TestPruneKeepsEverythingFromTheFirstUnpublishedEvent(t *testing.T) {
+	db := dbtest.Open(t)
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	var ids []uint64
+	for _, status := range []string{entity.OutboxPublished, entity.OutboxPublished, entity.OutboxDead, entity.OutboxPublished} {
+		event := queue(t, db, 1)
+		err := db.Model(&event).Updates(map[string]interface{}{"status": status, "created_at": old}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, event.ID)
+	}
+
+	n, err := outbox.Prune(context.Background(), db, 24*time.Hour)
+	if err != nil || n != 2 {
+		t.Fatalf("Prune: got %d, %v, want the two published before the dead event", n, err)
+	}
+	var left []uint64
+	if err := db.Model(&entity.OutboxEvent{}).Order("id").Pluck("id", &left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 2 || left[0] != ids[2] || left[1] != ids[3] {
+		t.Fatalf("left %v, want %v", left, ids[2:])
+	}
+
+	// With nothing but old published events, the newest stays.
+	db.Model(&entity.OutboxEvent{}).Where("id = ?", ids[2]).Update("status", entity.OutboxPublished)
+	if n, err := outbox.Prune(context.Background(), db, 24*time.Hour); err != nil || n != 1 {
+		t.Fatalf("second Prune: got %d, %v", n, err)
+	}
+}
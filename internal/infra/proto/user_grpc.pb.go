@@ -2,7 +2,7 @@
 // versions:
 // - protoc-gen-go-grpc v1.2.0
 // - protoc             v3.21.5
-// source: ploto/user.proto
+// source: proto/user.proto
 
 package protos
 
@@ -26,6 +26,17 @@ type UserCrudClient interface {
 	Update(ctx context.Context, in *User, opts ...grpc.CallOption) (*Response, error)
 	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*Users, error)
 	Delete(ctx context.Context, in *User, opts ...grpc.CallOption) (*Response, error)
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*User, error)
+	BatchGetUsers(ctx context.Context, in *BatchGetUsersRequest, opts ...grpc.CallOption) (*BatchGetUsersResponse, error)
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*AuditEvents, error)
+	RevealUser(ctx context.Context, in *RevealUserRequest, opts ...grpc.CallOption) (*User, error)
+	SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*Users, error)
+	UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*Response, error)
+	StreamUsers(ctx context.Context, in *StreamUsersRequest, opts ...grpc.CallOption) (UserCrud_StreamUsersClient, error)
+	// BulkUpsertUsers inserts the users sent, or updates those whose
+	// document is already registered, in batches committed as they fill.
+	BulkUpsertUsers(ctx context.Context, opts ...grpc.CallOption) (UserCrud_BulkUpsertUsersClient, error)
+	WatchUsers(ctx context.Context, in *WatchUsersRequest, opts ...grpc.CallOption) (UserCrud_WatchUsersClient, error)
 }
 
 type userCrudClient struct {
@@ -72,6 +83,158 @@ func (c *userCrudClient) Delete(ctx context.Context, in *User, opts ...grpc.Call
 	return out, nil
 }
 
+func (c *userCrudClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/api.v1.UserCrud/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userCrudClient) BatchGetUsers(ctx context.Context, in *BatchGetUsersRequest, opts ...grpc.CallOption) (*BatchGetUsersResponse, error) {
+	out := new(BatchGetUsersResponse)
+	err := c.cc.Invoke(ctx, "/api.v1.UserCrud/BatchGetUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userCrudClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*AuditEvents, error) {
+	out := new(AuditEvents)
+	err := c.cc.Invoke(ctx, "/api.v1.UserCrud/ListAuditEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userCrudClient) RevealUser(ctx context.Context, in *RevealUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/api.v1.UserCrud/RevealUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userCrudClient) SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*Users, error) {
+	out := new(Users)
+	err := c.cc.Invoke(ctx, "/api.v1.UserCrud/SearchUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userCrudClient) UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/api.v1.UserCrud/UpdateUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userCrudClient) StreamUsers(ctx context.Context, in *StreamUsersRequest, opts ...grpc.CallOption) (UserCrud_StreamUsersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &UserCrud_ServiceDesc.Streams[0], "/api.v1.UserCrud/StreamUsers", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &userCrudStreamUsersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type UserCrud_StreamUsersClient interface {
+	Recv() (*User, error)
+	grpc.ClientStream
+}
+
+type userCrudStreamUsersClient struct {
+	grpc.ClientStream
+}
+
+func (x *userCrudStreamUsersClient) Recv() (*User, error) {
+	m := new(User)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *userCrudClient) BulkUpsertUsers(ctx context.Context, opts ...grpc.CallOption) (UserCrud_BulkUpsertUsersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &UserCrud_ServiceDesc.Streams[1], "/api.v1.UserCrud/BulkUpsertUsers", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &userCrudBulkUpsertUsersClient{stream}
+	return x, nil
+}
+
+type UserCrud_BulkUpsertUsersClient interface {
+	Send(*User) error
+	CloseAndRecv() (*BulkUpsertUsersResponse, error)
+	grpc.ClientStream
+}
+
+type userCrudBulkUpsertUsersClient struct {
+	grpc.ClientStream
+}
+
+func (x *userCrudBulkUpsertUsersClient) Send(m *User) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *userCrudBulkUpsertUsersClient) CloseAndRecv() (*BulkUpsertUsersResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BulkUpsertUsersResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *userCrudClient) WatchUsers(ctx context.Context, in *WatchUsersRequest, opts ...grpc.CallOption) (UserCrud_WatchUsersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &UserCrud_ServiceDesc.Streams[2], "/api.v1.UserCrud/WatchUsers", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &userCrudWatchUsersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type UserCrud_WatchUsersClient interface {
+	Recv() (*UserChange, error)
+	grpc.ClientStream
+}
+
+type userCrudWatchUsersClient struct {
+	grpc.ClientStream
+}
+
+func (x *userCrudWatchUsersClient) Recv() (*UserChange, error) {
+	m := new(UserChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // UserCrudServer is the server API for UserCrud service.
 // All implementations must embed UnimplementedUserCrudServer
 // for forward compatibility
@@ -80,6 +243,17 @@ type UserCrudServer interface {
 	Update(context.Context, *User) (*Response, error)
 	List(context.Context, *ListRequest) (*Users, error)
 	Delete(context.Context, *User) (*Response, error)
+	Get(context.Context, *GetRequest) (*User, error)
+	BatchGetUsers(context.Context, *BatchGetUsersRequest) (*BatchGetUsersResponse, error)
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*AuditEvents, error)
+	RevealUser(context.Context, *RevealUserRequest) (*User, error)
+	SearchUsers(context.Context, *SearchUsersRequest) (*Users, error)
+	UpdateUser(context.Context, *UpdateUserRequest) (*Response, error)
+	StreamUsers(*StreamUsersRequest, UserCrud_StreamUsersServer) error
+	// BulkUpsertUsers inserts the users sent, or updates those whose
+	// document is already registered, in batches committed as they fill.
+	BulkUpsertUsers(UserCrud_BulkUpsertUsersServer) error
+	WatchUsers(*WatchUsersRequest, UserCrud_WatchUsersServer) error
 	mustEmbedUnimplementedUserCrudServer()
 }
 
@@ -99,6 +273,33 @@ func (UnimplementedUserCrudServer) List(context.Context, *ListRequest) (*Users,
 func (UnimplementedUserCrudServer) Delete(context.Context, *User) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedUserCrudServer) Get(context.Context, *GetRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedUserCrudServer) BatchGetUsers(context.Context, *BatchGetUsersRequest) (*BatchGetUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetUsers not implemented")
+}
+func (UnimplementedUserCrudServer) ListAuditEvents(context.Context, *ListAuditEventsRequest) (*AuditEvents, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (UnimplementedUserCrudServer) RevealUser(context.Context, *RevealUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevealUser not implemented")
+}
+func (UnimplementedUserCrudServer) SearchUsers(context.Context, *SearchUsersRequest) (*Users, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchUsers not implemented")
+}
+func (UnimplementedUserCrudServer) UpdateUser(context.Context, *UpdateUserRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUser not implemented")
+}
+func (UnimplementedUserCrudServer) StreamUsers(*StreamUsersRequest, UserCrud_StreamUsersServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamUsers not implemented")
+}
+func (UnimplementedUserCrudServer) BulkUpsertUsers(UserCrud_BulkUpsertUsersServer) error {
+	return status.Errorf(codes.Unimplemented, "method BulkUpsertUsers not implemented")
+}
+func (UnimplementedUserCrudServer) WatchUsers(*WatchUsersRequest, UserCrud_WatchUsersServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchUsers not implemented")
+}
 func (UnimplementedUserCrudServer) mustEmbedUnimplementedUserCrudServer() {}
 
 // UnsafeUserCrudServer may be embedded to opt out of forward compatibility for this service.
@@ -184,6 +385,182 @@ func _UserCrud_Delete_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserCrud_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserCrudServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.v1.UserCrud/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserCrudServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserCrud_BatchGetUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserCrudServer).BatchGetUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.v1.UserCrud/BatchGetUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserCrudServer).BatchGetUsers(ctx, req.(*BatchGetUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserCrud_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserCrudServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.v1.UserCrud/ListAuditEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserCrudServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserCrud_RevealUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevealUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserCrudServer).RevealUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.v1.UserCrud/RevealUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserCrudServer).RevealUser(ctx, req.(*RevealUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserCrud_SearchUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserCrudServer).SearchUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.v1.UserCrud/SearchUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserCrudServer).SearchUsers(ctx, req.(*SearchUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserCrud_UpdateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserCrudServer).UpdateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.v1.UserCrud/UpdateUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserCrudServer).UpdateUser(ctx, req.(*UpdateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserCrud_StreamUsers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamUsersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UserCrudServer).StreamUsers(m, &userCrudStreamUsersServer{stream})
+}
+
+type UserCrud_StreamUsersServer interface {
+	Send(*User) error
+	grpc.ServerStream
+}
+
+type userCrudStreamUsersServer struct {
+	grpc.ServerStream
+}
+
+func (x *userCrudStreamUsersServer) Send(m *User) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _UserCrud_BulkUpsertUsers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(UserCrudServer).BulkUpsertUsers(&userCrudBulkUpsertUsersServer{stream})
+}
+
+type UserCrud_BulkUpsertUsersServer interface {
+	SendAndClose(*BulkUpsertUsersResponse) error
+	Recv() (*User, error)
+	grpc.ServerStream
+}
+
+type userCrudBulkUpsertUsersServer struct {
+	grpc.ServerStream
+}
+
+func (x *userCrudBulkUpsertUsersServer) SendAndClose(m *BulkUpsertUsersResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *userCrudBulkUpsertUsersServer) Recv() (*User, error) {
+	m := new(User)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _UserCrud_WatchUsers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchUsersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UserCrudServer).WatchUsers(m, &userCrudWatchUsersServer{stream})
+}
+
+type UserCrud_WatchUsersServer interface {
+	Send(*UserChange) error
+	grpc.ServerStream
+}
+
+type userCrudWatchUsersServer struct {
+	grpc.ServerStream
+}
+
+func (x *userCrudWatchUsersServer) Send(m *UserChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // UserCrud_ServiceDesc is the grpc.ServiceDesc for UserCrud service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -207,7 +584,47 @@ var UserCrud_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _UserCrud_Delete_Handler,
 		},
+		{
+			MethodName: "Get",
+			Handler:    _UserCrud_Get_Handler,
+		},
+		{
+			MethodName: "BatchGetUsers",
+			Handler:    _UserCrud_BatchGetUsers_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _UserCrud_ListAuditEvents_Handler,
+		},
+		{
+			MethodName: "RevealUser",
+			Handler:    _UserCrud_RevealUser_Handler,
+		},
+		{
+			MethodName: "SearchUsers",
+			Handler:    _UserCrud_SearchUsers_Handler,
+		},
+		{
+			MethodName: "UpdateUser",
+			Handler:    _UserCrud_UpdateUser_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamUsers",
+			Handler:       _UserCrud_StreamUsers_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "BulkUpsertUsers",
+			Handler:       _UserCrud_BulkUpsertUsers_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchUsers",
+			Handler:       _UserCrud_WatchUsers_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
-	Metadata: "ploto/user.proto",
+	Metadata: "proto/user.proto",
 }
@@ -0,0 +1,172 @@
+// Package seed loads fixture files into the database. Fixtures live in
+// <dir>/<profile>/ as YAML or JSON files applied in file name order, e.g.
+//
+//	tenant: acme
+//	users:
+//	  - name: Ana
+//	    document: "123"
+//	    phone: "555"
+//
+// Rows are matched by their natural key, so seeding twice changes nothing.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	repository "template-grpc/internal/domain/repository/implement/user"
+	ireposity "template-grpc/internal/domain/repository/interface"
+	"template-grpc/internal/infra/database"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Actor stamps the rows written by the seeder.
+const Actor = "seed"
+
+// tables are emptied by truncate, children first, each through the
+// condition selecting the rows of the seeded tenants.
+var tables = []struct{ name, where string }{
+	{"user_search_tokens", "user_id IN (SELECT id FROM users WHERE tenant_id IN ?)"},
+	{"audit_log", "tenant_id IN ?"},
+	{"outbox", "tenant IN ?"},
+	{"idempotency_keys", "tenant_id IN ?"},
+	{"users", "tenant_id IN ?"},
+}
+
+// Fixture is the content of one file; Tenant owns every row in it.
+type Fixture struct {
+	File   string `mapstructure:"-"`
+	Tenant string
+	Users  []entity.User
+}
+
+// Result counts what a run did to the rows of the fixtures.
+type Result struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
+}
+
+// Load reads the fixtures of a profile.
+func Load(dir, profile string) ([]Fixture, error) {
+	root := filepath.Join(dir, profile)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("profile %q: %w", profile, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yml", ".yaml", ".json":
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+
+	fixtures := make([]Fixture, 0, len(names))
+	for _, name := range names {
+		fixture, err := LoadFile(filepath.Join(root, name))
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
+
+// LoadFile reads a single YAML or JSON fixture.
+func LoadFile(path string) (Fixture, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return Fixture{}, fmt.Errorf("%s: %w", path, err)
+	}
+	fixture := Fixture{File: path}
+	if err := v.Unmarshal(&fixture); err != nil {
+		return Fixture{}, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, user := range fixture.Users {
+		if user.Document == "" {
+			return Fixture{}, fmt.Errorf("%s: user %d has no document", path, i)
+		}
+	}
+	return fixture, nil
+}
+
+// Run applies the fixtures in one transaction, writing through the user
+// repository so every change leaves its audit event and outbox event like
+// any other write. With truncate the users of the fixtures' tenants are
+// deleted first, with their search tokens, audit log, outbox events and
+// idempotency keys; other tenants are left alone.
+func Run(ctx context.Context, db *gorm.DB, fixtures []Fixture, truncate bool) (Result, error) {
+	var result Result
+	cluster := database.NewCluster(db, nil, database.Random)
+	err := database.NewTransactor(cluster, database.DefaultRetries).Transaction(ctx, func(ctx context.Context, tx database.Resolver) error {
+		result = Result{}
+		if truncate {
+			tenants := make([]string, 0, len(fixtures))
+			for _, fixture := range fixtures {
+				tenants = append(tenants, fixture.Tenant)
+			}
+			for _, table := range tables {
+				if err := tx.Writer(ctx).Exec("DELETE FROM "+table.name+" WHERE "+table.where, tenants).Error; err != nil {
+					return err
+				}
+			}
+		}
+		users := repository.NewRepository(tx)
+		for _, fixture := range fixtures {
+			ctx := identity.NewContext(ctx, identity.Identity{Actor: Actor, Tenant: fixture.Tenant})
+			for _, user := range fixture.Users {
+				if err := upsertUser(ctx, tx, users, user, &result); err != nil {
+					return fmt.Errorf("%s: user %s: %w", fixture.File, user.Document, err)
+				}
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// upsertUser matches users by document, restoring soft deleted ones since
+// their document stays reserved. The repository has no restore, so that
+// one column is cleared here and the update that follows records it.
+func upsertUser(ctx context.Context, tx database.Resolver, users ireposity.IUserCrud, user entity.User, result *Result) error {
+	hash, err := database.BlindIndex(user.Document)
+	if err != nil {
+		return err
+	}
+	var existing entity.User
+	found := tx.Writer(ctx).Unscoped().Where("document_hash = ?", hash).Limit(1).Find(&existing)
+	if found.Error != nil {
+		return found.Error
+	}
+	if found.RowsAffected == 0 {
+		result.Inserted++
+		return users.Insert(ctx, user).Err()
+	}
+
+	if existing.Name == user.Name && existing.Phone == user.Phone && !existing.DeletedAt.Valid {
+		result.Unchanged++
+		return nil
+	}
+	result.Updated++
+	if existing.DeletedAt.Valid {
+		err := tx.Writer(ctx).Unscoped().Model(&existing).Update("deleted_at", nil).Error
+		if err != nil {
+			return err
+		}
+	}
+	user.ID, user.Version = existing.ID, existing.Version
+	return users.Update(ctx, user).Err()
+}
@@ -0,0 +1,135 @@
+package seed_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"template-grpc/internal/domain/entity"
+	"template-grpc/internal/domain/identity"
+	repository "template-grpc/internal/domain/repository/implement/user"
+	"template-grpc/internal/infra/database"
+	"template-grpc/internal/infra/database/dbtest"
+	"template-grpc/internal/infra/seed"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func count(t *testing.T, db *gorm.DB, table, where string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Table(table).Where(where, args...).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func load(t *testing.T) []seed.Fixture {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "dev"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fixture := "tenant: acme\nusers:\n  - name: Ana\n    document: \"1001\"\n    phone: \"555-1001\"\n  - name: Beto\n    document: \"1002\"\n    phone: \"555-1002\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "dev", "users.yml"), []byte(fixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := seed.Load(dir, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixtures
+}
+
+func TestRunWritesThroughTheRepository(t *testing.T) {
+	db := dbtest.Open(t)
+	fixtures := load(t)
+
+	result, err := seed.Run(context.Background(), db, fixtures, false)
+	if err != nil || result != (seed.Result{Inserted: 2}) {
+		t.Fatalf("first run: got %+v, %v", result, err)
+	}
+	if n := count(t, db, "audit_log", "tenant_id = ? AND actor = ?", "acme", seed.Actor); n != 2 {
+		t.Fatalf("%d audit events, want one per inserted user", n)
+	}
+	if n := count(t, db, "outbox", "tenant = ? AND type = ?", "acme", entity.EventUserCreated); n != 2 {
+		t.Fatalf("%d outbox events, want one per inserted user", n)
+	}
+	if n := count(t, db, "user_search_tokens", "1 = 1"); n == 0 {
+		t.Fatal("the seeded users have no search tokens")
+	}
+
+	result, err = seed.Run(context.Background(), db, fixtures, false)
+	if err != nil || result != (seed.Result{Unchanged: 2}) {
+		t.Fatalf("second run: got %+v, %v", result, err)
+	}
+	if n := count(t, db, "outbox", "1 = 1"); n != 2 {
+		t.Fatalf("%d outbox events after a run that changed nothing", n)
+	}
+}
+
+func TestRunRestoresADeletedUser(t *testing.T) {
+	db := dbtest.Open(t)
+	fixtures := load(t)
+	if _, err := seed.Run(context.Background(), db, fixtures, false); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := identity.NewContext(context.Background(), identity.Identity{Actor: "alice", Tenant: "acme"})
+	hash, err := database.BlindIndex("1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var user entity.User
+	if err := db.WithContext(ctx).Where("document_hash = ?", hash).First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if res := repository.NewRepository(database.NewCluster(db, nil, database.Random)).Delete(ctx, user.ID); !res.IsOk {
+		t.Fatalf("delete: %s", res.Message)
+	}
+
+	result, err := seed.Run(context.Background(), db, fixtures, false)
+	if err != nil || result != (seed.Result{Updated: 1, Unchanged: 1}) {
+		t.Fatalf("got %+v, %v", result, err)
+	}
+	var restored entity.User
+	if err := db.WithContext(ctx).First(&restored, user.ID).Error; err != nil {
+		t.Fatalf("the user was not restored: %v", err)
+	}
+	if n := count(t, db, "outbox", "type = ?", entity.EventUserUpdated); n != 1 {
+		t.Fatalf("%d update events, want the restore's", n)
+	}
+}
+
+func TestTruncateKeepsOtherTenants(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := identity.NewContext(context.Background(), identity.Identity{Actor: "alice", Tenant: "globex"})
+	other := repository.NewRepository(database.NewCluster(db, nil, database.Random)).Insert(ctx, entity.User{Name: "Otro", Document: "2001", Phone: "555-2001"})
+	if !other.IsOk {
+		t.Fatalf("insert: %s", other.Message)
+	}
+	fixtures := load(t)
+	if _, err := seed.Run(context.Background(), db, fixtures, false); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := seed.Run(context.Background(), db, fixtures, true)
+	if err != nil || result != (seed.Result{Inserted: 2}) {
+		t.Fatalf("got %+v, %v", result, err)
+	}
+	if n := count(t, db, "users", "tenant_id = ?", "acme"); n != 2 {
+		t.Fatalf("%d acme users, want the fixtures only", n)
+	}
+	if n := count(t, db, "audit_log", "tenant_id = ?", "acme"); n != 2 {
+		t.Fatalf("%d acme audit events, want the last run's", n)
+	}
+	if n := count(t, db, "users", "tenant_id = ?", "globex"); n != 1 {
+		t.Fatal("truncate deleted another tenant's user")
+	}
+	if n := count(t, db, "user_search_tokens", "user_id = ?", other.ID); n == 0 {
+		t.Fatal("truncate deleted another tenant's search tokens")
+	}
+	if n := count(t, db, "outbox", "tenant = ?", "globex"); n != 1 {
+		t.Fatal("truncate deleted another tenant's outbox events")
+	}
+}